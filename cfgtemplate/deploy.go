@@ -0,0 +1,187 @@
+package cfgtemplate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	cvpgo "github.com/fredhsu/cvpgo/client"
+)
+
+// DeployOptions selects what Deploy renders. Devices and Containers name
+// the targets by hostname and container name, when both are empty every
+// device with a variable file is a target.
+type DeployOptions struct {
+	Devices    []string
+	Containers []string
+	// DryRun renders the configlets without changing anything in CVP
+	DryRun bool
+	// Save commits the configlet assignments, otherwise they are left as
+	// temp actions
+	Save bool
+}
+
+// DeployResult is the configlet rendered for one target
+type DeployResult struct {
+	Target    string
+	Configlet cvpgo.Configlet
+	Upsert    cvpgo.UpsertResult
+	// Assigned is set when the configlet was newly assigned to the target
+	Assigned bool
+}
+
+// ConfigletName returns the name of the configlet rendered from tmpl for a
+// target
+func ConfigletName(tmpl *Template, target string) string {
+	return tmpl.Name + "_" + target
+}
+
+// Deploy renders tmpl for every target with the target's variables,
+// creates or updates the resulting configlets and assigns them to their
+// target in one batch of temp actions. Configlets already assigned to a
+// target stay assigned, a configlet a device already inherits from its
+// containers is not assigned to it again. It returns the IDs of the tasks created by updated
+// configlets and, when opts.Save is set, by the assignments.
+func Deploy(ctx context.Context, c *cvpgo.CvpClient, tmpl *Template, vars *VarSet, opts DeployOptions) ([]DeployResult, []string, error) {
+	devices, err := c.ListDevices(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	containers, err := c.ListContainers(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	containerByName := make(map[string]cvpgo.Container, len(containers))
+	containerByKey := make(map[string]cvpgo.Container, len(containers))
+	for _, container := range containers {
+		containerByName[container.Name] = container
+		containerByKey[container.Key] = container
+	}
+	deviceByName := make(map[string]cvpgo.NetElement, len(devices))
+	for _, dev := range devices {
+		deviceByName[strings.SplitN(dev.Fqdn, ".", 2)[0]] = dev
+		deviceByName[dev.Fqdn] = dev
+	}
+
+	targets := opts.Devices
+	if len(opts.Devices) == 0 && len(opts.Containers) == 0 {
+		for hostname := range vars.Devices {
+			targets = append(targets, hostname)
+		}
+	}
+	var results []DeployResult
+	var actions []cvpgo.Action
+	var taskIds []string
+	for _, hostname := range sortedCopy(targets) {
+		dev, ok := deviceByName[hostname]
+		if !ok {
+			return results, taskIds, fmt.Errorf("No device named \"%s\" found", hostname)
+		}
+		container := containerByKey[dev.ParentContainerKey].Name
+		result, ids, err := deploy(ctx, c, tmpl, hostname, vars.For(container, hostname), opts.DryRun)
+		taskIds = append(taskIds, ids...)
+		if err != nil {
+			return results, taskIds, err
+		}
+		if !opts.DryRun {
+			current, err := c.GetConfigletByDeviceIDWithContext(ctx, dev.SystemMacAddress)
+			if err != nil {
+				return results, taskIds, err
+			}
+			// the device lookup also returns the configlets the device
+			// inherits, which must not be assigned to the device itself
+			inherited, err := c.InheritedConfiglets(ctx, dev.ParentContainerKey)
+			if err != nil {
+				return results, taskIds, err
+			}
+			if _, ok := withConfiglet(current, result.Configlet); ok {
+				assigned, _ := withConfiglet(cvpgo.WithoutConfiglets(current, inherited), result.Configlet)
+				actions = append(actions, cvpgo.DeviceConfigletsAction(dev, assigned))
+				result.Assigned = true
+			}
+		}
+		results = append(results, result)
+	}
+	for _, name := range sortedCopy(opts.Containers) {
+		container, ok := containerByName[name]
+		if !ok {
+			return results, taskIds, fmt.Errorf("No container named \"%s\" found", name)
+		}
+		result, ids, err := deploy(ctx, c, tmpl, name, vars.For(name, ""), opts.DryRun)
+		taskIds = append(taskIds, ids...)
+		if err != nil {
+			return results, taskIds, err
+		}
+		if !opts.DryRun {
+			current, err := c.GetConfigletByContainerID(ctx, container.Key)
+			if err != nil {
+				return results, taskIds, err
+			}
+			if assigned, ok := withConfiglet(current, result.Configlet); ok {
+				actions = append(actions, cvpgo.ContainerConfigletsAction(container, assigned))
+				result.Assigned = true
+			}
+		}
+		results = append(results, result)
+	}
+	if len(actions) == 0 {
+		return results, taskIds, nil
+	}
+	if err = c.AddTempActions(ctx, actions); err != nil {
+		return results, taskIds, err
+	}
+	if opts.Save {
+		sdata, err := c.SaveTopology(ctx)
+		if err != nil {
+			return results, taskIds, err
+		}
+		taskIds = append(taskIds, sdata.Data.TaskIds...)
+	}
+	return results, taskIds, nil
+}
+
+// deploy renders the configlet of one target and, unless dryRun is set,
+// creates or updates it
+func deploy(ctx context.Context, c *cvpgo.CvpClient, tmpl *Template, target string, vars Vars, dryRun bool) (DeployResult, []string, error) {
+	result := DeployResult{Target: target}
+	config, err := tmpl.Render(vars)
+	if err != nil {
+		return result, nil, fmt.Errorf("Error rendering %s for %s : %s", tmpl.Name, target, err)
+	}
+	result.Configlet = cvpgo.Configlet{Name: ConfigletName(tmpl, target), Config: config}
+	if dryRun {
+		return result, nil, nil
+	}
+	upsert, ids, err := c.UpsertConfiglet(ctx, result.Configlet)
+	if err != nil {
+		return result, ids, err
+	}
+	result.Upsert = upsert
+	cfglet, found, err := c.FindConfiglet(ctx, result.Configlet.Name)
+	if err != nil {
+		return result, ids, err
+	}
+	if !found {
+		return result, ids, fmt.Errorf("No configlet named \"%s\" found", result.Configlet.Name)
+	}
+	result.Configlet = cfglet
+	return result, ids, nil
+}
+
+// withConfiglet returns current with cfglet appended, or false if cfglet
+// is already assigned
+func withConfiglet(current []cvpgo.Configlet, cfglet cvpgo.Configlet) ([]cvpgo.Configlet, bool) {
+	for _, assigned := range current {
+		if assigned.Key == cfglet.Key {
+			return current, false
+		}
+	}
+	return append(current, cfglet), true
+}
+
+func sortedCopy(names []string) []string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,248 @@
+package cfgtemplate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Funcs are the helper functions available in templates:
+//
+//	ipAdd "10.0.0.1" 3                 10.0.0.4
+//	cidrHost "10.0.0.0/24" 5           10.0.0.5
+//	cidrNetmask "10.0.0.0/24"          255.255.255.0
+//	cidrPrefix "10.0.0.0/24"           24
+//	cidrSubnet "10.0.0.0/16" 8 3       10.0.3.0/24
+//	interfaces "Ethernet1-3,5"         [Ethernet1 Ethernet2 Ethernet3 Ethernet5]
+//	vlans "10-12,20"                   [10 11 12 20]
+//	vlanRanges (vlans "20,10-12")      10-12,20
+//	seq 1 3                            [1 2 3]
+//	join ", " (list)                   joined elements
+//	indent 3 "text"                    text with every line indented
+var Funcs = template.FuncMap{
+	"ipAdd":       ipAdd,
+	"cidrHost":    cidrHost,
+	"cidrNetmask": cidrNetmask,
+	"cidrPrefix":  cidrPrefix,
+	"cidrSubnet":  cidrSubnet,
+	"interfaces":  interfaces,
+	"vlans":       vlans,
+	"vlanRanges":  vlanRanges,
+	"seq":         seq,
+	"join":        join,
+	"indent":      indent,
+}
+
+func parseIPv4(s string) (uint32, error) {
+	ip := net.ParseIP(s).To4()
+	if ip == nil {
+		return 0, fmt.Errorf("Invalid IPv4 address \"%s\"", s)
+	}
+	return binary.BigEndian.Uint32(ip), nil
+}
+
+func formatIPv4(n uint32) string {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip.String()
+}
+
+func parseCIDR(s string) (uint32, int, error) {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil || network.IP.To4() == nil {
+		return 0, 0, fmt.Errorf("Invalid IPv4 prefix \"%s\"", s)
+	}
+	prefix, _ := network.Mask.Size()
+	return binary.BigEndian.Uint32(network.IP.To4()), prefix, nil
+}
+
+// ipAdd returns the address n addresses after ip, n may be negative
+func ipAdd(ip string, n int) (string, error) {
+	addr, err := parseIPv4(ip)
+	if err != nil {
+		return "", err
+	}
+	sum := int64(addr) + int64(n)
+	if sum < 0 || sum > 1<<32-1 {
+		return "", fmt.Errorf("%s + %d is not an IPv4 address", ip, n)
+	}
+	return formatIPv4(uint32(sum)), nil
+}
+
+// cidrHost returns host number n of a prefix
+func cidrHost(prefix string, n int) (string, error) {
+	network, bits, err := parseCIDR(prefix)
+	if err != nil {
+		return "", err
+	}
+	if n < 0 || uint64(n) >= 1<<uint(32-bits) {
+		return "", fmt.Errorf("Prefix %s has no host number %d", prefix, n)
+	}
+	return formatIPv4(network + uint32(n)), nil
+}
+
+// cidrNetmask returns the dotted netmask of a prefix
+func cidrNetmask(prefix string) (string, error) {
+	_, bits, err := parseCIDR(prefix)
+	if err != nil {
+		return "", err
+	}
+	return net.IP(net.CIDRMask(bits, 32)).String(), nil
+}
+
+// cidrPrefix returns the prefix length of a prefix
+func cidrPrefix(prefix string) (int, error) {
+	_, bits, err := parseCIDR(prefix)
+	return bits, err
+}
+
+// cidrSubnet returns subnet number n of a prefix extended by newbits
+func cidrSubnet(prefix string, newbits, n int) (string, error) {
+	network, bits, err := parseCIDR(prefix)
+	if err != nil {
+		return "", err
+	}
+	if newbits < 0 || bits+newbits > 32 {
+		return "", fmt.Errorf("Prefix %s cannot be extended by %d bits", prefix, newbits)
+	}
+	if n < 0 || uint64(n) >= 1<<uint(newbits) {
+		return "", fmt.Errorf("Prefix %s has no subnet number %d of %d bits", prefix, n, newbits)
+	}
+	subnet := network | uint32(n)<<uint(32-bits-newbits)
+	return fmt.Sprintf("%s/%d", formatIPv4(subnet), bits+newbits), nil
+}
+
+// parseRange parses "a-b" or "a" into its bounds
+func parseRange(s string) (int, int, error) {
+	parts := strings.SplitN(s, "-", 2)
+	from, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid range \"%s\"", s)
+	}
+	to := from
+	if len(parts) == 2 {
+		if to, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil || to < from {
+			return 0, 0, fmt.Errorf("Invalid range \"%s\"", s)
+		}
+	}
+	return from, to, nil
+}
+
+var interfaceRange = regexp.MustCompile(`^([A-Za-z-]+(?:[0-9]+/)*)([0-9]+(?:-[0-9]+)?)$`)
+
+// interfaces expands an EOS interface range such as "Ethernet1-3,5" or
+// "Ethernet1/1-4". Elements without a name reuse the previous name, so
+// "Ethernet1-2,7" is the same as "Ethernet1-2,Ethernet7".
+func interfaces(spec string) ([]string, error) {
+	var result []string
+	prefix := ""
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if m := interfaceRange.FindStringSubmatch(item); m != nil {
+			prefix, item = m[1], m[2]
+		} else if prefix == "" {
+			return nil, fmt.Errorf("Invalid interface range \"%s\"", spec)
+		}
+		from, to, err := parseRange(item)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid interface range \"%s\"", spec)
+		}
+		for i := from; i <= to; i++ {
+			result = append(result, prefix+strconv.Itoa(i))
+		}
+	}
+	return result, nil
+}
+
+// vlans expands a VLAN list such as "10-12,20" into sorted, unique VLAN IDs
+func vlans(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	var result []int
+	for _, item := range strings.Split(spec, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		from, to, err := parseRange(item)
+		if err != nil || from < 1 || to > 4094 {
+			return nil, fmt.Errorf("Invalid VLAN list \"%s\"", spec)
+		}
+		for v := from; v <= to; v++ {
+			if !seen[v] {
+				seen[v] = true
+				result = append(result, v)
+			}
+		}
+	}
+	sort.Ints(result)
+	return result, nil
+}
+
+// vlanRanges compacts VLAN IDs into EOS range syntax such as "10-12,20"
+func vlanRanges(ids []int) string {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[i] == sorted[j] {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// seq returns the integers from start to end inclusive
+func seq(start, end int) []int {
+	var result []int
+	for i := start; i <= end; i++ {
+		result = append(result, i)
+	}
+	return result
+}
+
+// join joins the elements of a list of any type
+func join(sep string, list interface{}) (string, error) {
+	switch l := list.(type) {
+	case []string:
+		return strings.Join(l, sep), nil
+	case []int:
+		parts := make([]string, len(l))
+		for i, v := range l {
+			parts[i] = strconv.Itoa(v)
+		}
+		return strings.Join(parts, sep), nil
+	case []interface{}:
+		parts := make([]string, len(l))
+		for i, v := range l {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, sep), nil
+	}
+	return "", fmt.Errorf("Cannot join %T", list)
+}
+
+// indent indents every non empty line of text by n spaces
+func indent(n int, text string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,67 @@
+package cfgtemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIPFuncs(t *testing.T) {
+	tests := []struct {
+		name string
+		got  func() (interface{}, error)
+		want interface{}
+	}{
+		{"ipAdd", func() (interface{}, error) { return ipAdd("10.0.0.254", 3) }, "10.0.1.1"},
+		{"ipAdd negative", func() (interface{}, error) { return ipAdd("10.0.1.0", -1) }, "10.0.0.255"},
+		{"cidrHost", func() (interface{}, error) { return cidrHost("10.0.0.0/24", 5) }, "10.0.0.5"},
+		{"cidrNetmask", func() (interface{}, error) { return cidrNetmask("10.0.0.0/22") }, "255.255.252.0"},
+		{"cidrPrefix", func() (interface{}, error) { return cidrPrefix("10.0.0.0/22") }, 22},
+		{"cidrSubnet", func() (interface{}, error) { return cidrSubnet("10.0.0.0/16", 8, 3) }, "10.0.3.0/24"},
+		{"cidrSubnet /31", func() (interface{}, error) { return cidrSubnet("10.255.0.0/24", 7, 5) }, "10.255.0.10/31"},
+	}
+	for _, test := range tests {
+		got, err := test.got()
+		if err != nil || got != test.want {
+			t.Errorf("%s = %v (%v), want %v", test.name, got, err, test.want)
+		}
+	}
+	if _, err := cidrHost("10.0.0.0/30", 4); err == nil {
+		t.Errorf("Expected an error for a host outside the prefix")
+	}
+	if _, err := ipAdd("10.0.0", 1); err == nil {
+		t.Errorf("Expected an error for an invalid address")
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"Ethernet1-3,5", []string{"Ethernet1", "Ethernet2", "Ethernet3", "Ethernet5"}},
+		{"Ethernet1/1-2, Ethernet2/1", []string{"Ethernet1/1", "Ethernet1/2", "Ethernet2/1"}},
+		{"Port-Channel10-11", []string{"Port-Channel10", "Port-Channel11"}},
+	}
+	for _, test := range tests {
+		got, err := interfaces(test.spec)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("interfaces(%q) = %v (%v), want %v", test.spec, got, err, test.want)
+		}
+	}
+	if _, err := interfaces("5-3"); err == nil {
+		t.Errorf("Expected an error for a range without an interface name")
+	}
+}
+
+func TestVlans(t *testing.T) {
+	got, err := vlans("20, 10-12,11")
+	if want := []int{10, 11, 12, 20}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("vlans = %v (%v), want %v", got, err, want)
+	}
+	if _, err = vlans("4090-4095"); err == nil {
+		t.Errorf("Expected an error for an invalid VLAN")
+	}
+	if got := vlanRanges([]int{20, 10, 11, 12, 30, 31}); got != "10-12,20,30-31" {
+		t.Errorf("vlanRanges = %s", got)
+	}
+}
@@ -0,0 +1,54 @@
+// Package cfgtemplate renders configlets from text/template templates and
+// per-container and per-device variables, and uploads and assigns them:
+//
+//	tmpl, err := cfgtemplate.ParseFile("templates/base.tmpl")
+//	vars, err := cfgtemplate.LoadVarDir("vars")
+//	results, taskIds, err := cfgtemplate.Deploy(ctx, cvp, tmpl, vars, cfgtemplate.DeployOptions{Save: true})
+//
+// Templates can use the helper functions in Funcs for IP math, interface
+// ranges and VLAN lists.
+package cfgtemplate
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Template is a configlet template
+type Template struct {
+	Name string
+	tmpl *template.Template
+}
+
+// Parse parses a template, references to missing variables are errors
+// when it is rendered
+func Parse(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Funcs(Funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{Name: name, tmpl: tmpl}, nil
+}
+
+// ParseFile parses the template in the named file, the template is named
+// after the file without its extension
+func ParseFile(name string) (*Template, error) {
+	text, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(name)
+	return Parse(strings.TrimSuffix(base, filepath.Ext(base)), string(text))
+}
+
+// Render renders the template with vars
+func (t *Template) Render(vars Vars) (string, error) {
+	var b bytes.Buffer
+	if err := t.tmpl.Execute(&b, map[string]interface{}(vars)); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
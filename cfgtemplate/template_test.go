@@ -0,0 +1,71 @@
+package cfgtemplate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testTemplate = `hostname {{ .hostname }}
+{{- range $i, $intf := interfaces .uplinks }}
+interface {{ $intf }}
+   ip address {{ cidrSubnet $.p2p 7 $i }}
+{{- end }}
+vlan {{ vlanRanges (vlans .vlans) }}
+`
+
+func TestRender(t *testing.T) {
+	tmpl, err := Parse("leaf", testTemplate)
+	if err != nil {
+		t.Fatalf("Error parsing template : %s", err)
+	}
+	got, err := tmpl.Render(Vars{"hostname": "leaf1", "uplinks": "Ethernet1-2", "p2p": "10.255.0.0/24", "vlans": "12,10-11"})
+	if err != nil {
+		t.Fatalf("Error rendering template : %s", err)
+	}
+	want := `hostname leaf1
+interface Ethernet1
+   ip address 10.255.0.0/31
+interface Ethernet2
+   ip address 10.255.0.2/31
+vlan 10-12
+`
+	if got != want {
+		t.Errorf("Unexpected config\n%s\nwant\n%s", got, want)
+	}
+	if _, err = tmpl.Render(Vars{"hostname": "leaf1"}); err == nil {
+		t.Errorf("Expected an error for missing variables")
+	}
+}
+
+func TestVarDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfgtemplate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		CommonVarFile: "ntp: 10.0.0.1\nbgp:\n  asn: 65000\n  timers: [3, 9]\n",
+		filepath.Join(ContainerVarDir, "Leafs.yaml"): "bgp:\n  asn: 65100\n",
+		filepath.Join(DeviceVarDir, "leaf1.yaml"):    "ntp: 10.0.0.2\nbgp:\n  routerId: 1.1.1.1\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vs, err := LoadVarDir(dir)
+	if err != nil {
+		t.Fatalf("Error loading variables : %s", err)
+	}
+	tmpl, err := Parse("t", "{{.hostname}} {{.container}} {{.ntp}} {{.bgp.asn}} {{.bgp.routerId}} {{join \",\" .bgp.timers}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tmpl.Render(vs.For("Leafs", "leaf1"))
+	if want := "leaf1 Leafs 10.0.0.2 65100 1.1.1.1 3,9"; err != nil || got != want {
+		t.Errorf("Render = %q (%v), want %q", got, err, want)
+	}
+}
@@ -0,0 +1,129 @@
+package cfgtemplate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Vars are the variables a template is rendered with
+type Vars map[string]interface{}
+
+// Merge returns a copy of v with the variables of other added, other takes
+// precedence and nested maps are merged
+func (v Vars) Merge(other Vars) Vars {
+	result := make(Vars, len(v)+len(other))
+	for k, val := range v {
+		result[k] = val
+	}
+	for k, val := range other {
+		current, ok1 := result[k].(map[string]interface{})
+		next, ok2 := val.(map[string]interface{})
+		if ok1 && ok2 {
+			result[k] = map[string]interface{}(Vars(current).Merge(Vars(next)))
+			continue
+		}
+		result[k] = val
+	}
+	return result
+}
+
+// LoadVars reads variables from a YAML or JSON file
+func LoadVars(name string) (Vars, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	raw := make(map[interface{}]interface{})
+	if err = yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("Error decoding %s : %s", name, err)
+	}
+	return Vars(stringKeys(raw).(map[string]interface{})), nil
+}
+
+// stringKeys converts the maps decoded from YAML to maps with string keys,
+// as JSON decodes them
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = stringKeys(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = stringKeys(val)
+		}
+	}
+	return v
+}
+
+// VarSet holds the variables shared by all devices, those of containers
+// and those of devices, by container name and device hostname
+type VarSet struct {
+	Common     Vars
+	Containers map[string]Vars
+	Devices    map[string]Vars
+}
+
+// Variable files of a variable directory
+const (
+	CommonVarFile   = "common.yaml"
+	ContainerVarDir = "containers"
+	DeviceVarDir    = "devices"
+)
+
+// LoadVarDir reads a variable directory holding an optional common.yaml,
+// containers/<container>.yaml and devices/<hostname>.yaml files
+func LoadVarDir(dir string) (*VarSet, error) {
+	vs := &VarSet{Common: Vars{}, Containers: make(map[string]Vars), Devices: make(map[string]Vars)}
+	common, err := LoadVars(filepath.Join(dir, CommonVarFile))
+	if err == nil {
+		vs.Common = common
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err = loadVarFiles(filepath.Join(dir, ContainerVarDir), vs.Containers); err != nil {
+		return nil, err
+	}
+	if err = loadVarFiles(filepath.Join(dir, DeviceVarDir), vs.Devices); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+func loadVarFiles(dir string, into map[string]Vars) error {
+	for _, pattern := range []string{"*.yaml", "*.yml", "*.json"} {
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return err
+		}
+		for _, name := range files {
+			vars, err := LoadVars(name)
+			if err != nil {
+				return err
+			}
+			base := filepath.Base(name)
+			into[strings.TrimSuffix(base, filepath.Ext(base))] = vars
+		}
+	}
+	return nil
+}
+
+// For returns the variables of a device in a container, or of the
+// container itself when device is empty. Device variables take precedence
+// over container variables, which take precedence over common variables.
+// The hostname and container variables are set from the arguments unless
+// a variable file sets them.
+func (vs *VarSet) For(container, device string) Vars {
+	vars := Vars{"container": container}
+	if device != "" {
+		vars["hostname"] = device
+	}
+	return vars.Merge(vs.Common).Merge(vs.Containers[container]).Merge(vs.Devices[device])
+}
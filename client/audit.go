@@ -0,0 +1,145 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+)
+
+// Object types recorded in AuditEntry.Category
+const (
+	AuditConfiglet     = "Configlet"
+	AuditContainer     = "Container"
+	AuditNetElement    = "NetElement"
+	AuditTask          = "Task"
+	AuditImage         = "Image"
+	AuditUser          = "User"
+	AuditChangeControl = "ChangeControl"
+)
+
+// AuditEntry is one entry of CVP's audit log, recording a change made by a
+// user to an object
+type AuditEntry struct {
+	Key                  string `json:"key"`
+	Category             string `json:"category"`
+	ObjectKey            string `json:"objectKey"`
+	ObjectName           string `json:"objectName"`
+	Activity             string `json:"activity"`
+	Description          string `json:"description"`
+	UserName             string `json:"userName"`
+	DateTimeInLongFormat int64  `json:"dateTimeInLongFormat"`
+}
+
+// Time returns the time the change was made
+func (e AuditEntry) Time() time.Time {
+	return time.Unix(0, e.DateTimeInLongFormat*int64(time.Millisecond))
+}
+
+func (e AuditEntry) String() string {
+	return fmt.Sprintf("%s %s %s %s: %s", e.Time().Format(time.RFC3339), e.UserName, e.Category, e.ObjectName, e.Activity)
+}
+
+// AuditFilter selects the entries returned by ListAuditLogs, zero fields
+// match any entry. ObjectType matches the entry category exactly, as CVP
+// does, see the Audit constants, and Object the key or name of the changed
+// object. Start and End select a page of the matching entries, an End of 0
+// returns all of them.
+type AuditFilter struct {
+	User       string
+	ObjectType string
+	Object     string
+	Since      time.Time
+	Until      time.Time
+	Start      int
+	End        int
+}
+
+func (f AuditFilter) matches(e AuditEntry) bool {
+	if f.User != "" && f.User != e.UserName {
+		return false
+	}
+	if f.ObjectType != "" && f.ObjectType != e.Category {
+		return false
+	}
+	if f.Object != "" && f.Object != e.ObjectKey && f.Object != e.ObjectName {
+		return false
+	}
+	return inTimeRange(e.Time(), f.Since, f.Until)
+}
+
+// categoryOnly reports whether CVP can apply the whole filter itself
+func (f AuditFilter) categoryOnly() bool {
+	return f.User == "" && f.Object == "" && f.Since.IsZero() && f.Until.IsZero()
+}
+
+// inTimeRange reports whether t falls in [since, until), zero bounds are open
+func inTimeRange(t, since, until time.Time) bool {
+	if !since.IsZero() && t.Before(since) {
+		return false
+	}
+	if !until.IsZero() && !t.Before(until) {
+		return false
+	}
+	return true
+}
+
+// page returns the bounds of the page [start, end) of a list of n items,
+// an end of 0 selects everything from start
+func page(start, end, n int) (int, int) {
+	if end <= 0 || end > n {
+		end = n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > end {
+		start = end
+	}
+	return start, end
+}
+
+// ListAuditLogs returns the page of audit entries matching filter along
+// with the total number of matching entries. Entries keep CVP's order, most
+// recent first, so that a page is the same whether CVP or the client picks
+// it. CVP only filters by category, a filter on anything else fetches the
+// whole audit log and filters and pages it here.
+func (c *CvpClient) ListAuditLogs(ctx context.Context, filter AuditFilter) ([]AuditEntry, int, error) {
+	serverSide := filter.categoryOnly()
+	start, end := 0, 0
+	if serverSide {
+		start, end = filter.Start, filter.End
+	}
+	auditURL := fmt.Sprintf("/audit/getAuditLogs.do?category=%s&startIndex=%d&endIndex=%d",
+		url.QueryEscape(filter.ObjectType), start, end)
+	respbody, err := c.GetWithContext(ctx, auditURL)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp := struct {
+		Total        int          `json:"total"`
+		Data         []AuditEntry `json:"data"`
+		ErrorCode    string       `json:"errorCode"`
+		ErrorMessage string       `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListAuditLogs :%s\n", err)
+		return nil, 0, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, 0, err
+	}
+	if serverSide {
+		return resp.Data, resp.Total, nil
+	}
+	var entries []AuditEntry
+	for _, e := range resp.Data {
+		if filter.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	start, end = page(filter.Start, filter.End, len(entries))
+	return entries[start:end], len(entries), nil
+}
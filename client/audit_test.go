@@ -0,0 +1,106 @@
+package cvpgo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuditFilter(t *testing.T) {
+	now := time.Now()
+	entry := AuditEntry{
+		Category:             AuditConfiglet,
+		ObjectKey:            "configlet_1",
+		ObjectName:           "Test1",
+		UserName:             "cvpadmin",
+		DateTimeInLongFormat: now.UnixNano() / int64(time.Millisecond),
+	}
+	tests := []struct {
+		filter AuditFilter
+		want   bool
+	}{
+		{AuditFilter{}, true},
+		{AuditFilter{User: "cvpadmin", ObjectType: AuditConfiglet, Object: "Test1"}, true},
+		{AuditFilter{ObjectType: "configlet"}, false},
+		{AuditFilter{Object: "configlet_1", Since: now.Add(-time.Hour), Until: now.Add(time.Hour)}, true},
+		{AuditFilter{User: "someone"}, false},
+		{AuditFilter{ObjectType: AuditContainer}, false},
+		{AuditFilter{Since: now.Add(time.Hour)}, false},
+		{AuditFilter{Until: now.Add(-time.Hour)}, false},
+	}
+	for _, test := range tests {
+		if got := test.filter.matches(entry); got != test.want {
+			t.Errorf("%+v matches = %t, want %t", test.filter, got, test.want)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	tests := []struct {
+		start, end, n int
+		from, to      int
+	}{
+		{0, 0, 5, 0, 5},
+		{1, 3, 5, 1, 3},
+		{3, 10, 5, 3, 5},
+		{7, 10, 5, 5, 5},
+	}
+	for _, test := range tests {
+		if from, to := page(test.start, test.end, test.n); from != test.from || to != test.to {
+			t.Errorf("page(%d, %d, %d) = %d, %d, want %d, %d", test.start, test.end, test.n, from, to, test.from, test.to)
+		}
+	}
+}
+
+func TestListAuditLogs(t *testing.T) {
+	data := buildConfigletTestData()
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	entries, total, err := cvp.ListAuditLogs(context.Background(), AuditFilter{User: data.CvpUser, End: 10})
+	if err != nil {
+		t.Fatalf("Error listing audit logs : %s", err)
+	}
+	if len(entries) > 10 || total < len(entries) {
+		t.Errorf("Unexpected page of %d out of %d entries", len(entries), total)
+	}
+	for _, e := range entries {
+		if e.UserName != data.CvpUser {
+			t.Errorf("Unexpected entry %s", e)
+		}
+	}
+}
+
+func TestListAuditLogsPaging(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		fmt.Fprint(w, `{"total":3,"data":[
+			{"userName":"b","category":"Configlet","dateTimeInLongFormat":3},
+			{"userName":"a","category":"Configlet","dateTimeInLongFormat":1},
+			{"userName":"a","category":"Configlet","dateTimeInLongFormat":2}]}`)
+	}))
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL, Client: srv.Client()}
+	ctx := context.Background()
+
+	entries, total, err := cvp.ListAuditLogs(ctx, AuditFilter{ObjectType: AuditConfiglet, Start: 10, End: 20})
+	if err != nil {
+		t.Fatalf("Error listing audit logs : %s", err)
+	}
+	if queries[0] != "category=Configlet&startIndex=10&endIndex=20" || total != 3 || len(entries) != 3 {
+		t.Errorf("Expected CVP to page, got %s and %d of %d entries", queries[0], len(entries), total)
+	}
+	if entries[1].DateTimeInLongFormat != 1 || entries[2].DateTimeInLongFormat != 2 {
+		t.Errorf("Entries are not in CVP's order %v", entries)
+	}
+
+	entries, total, err = cvp.ListAuditLogs(ctx, AuditFilter{User: "a", End: 1})
+	if err != nil {
+		t.Fatalf("Error listing audit logs : %s", err)
+	}
+	if queries[1] != "category=&startIndex=0&endIndex=0" || total != 2 || len(entries) != 1 || entries[0].DateTimeInLongFormat != 1 {
+		t.Errorf("Unexpected filtered page %s %v of %d", queries[1], entries, total)
+	}
+}
@@ -0,0 +1,320 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+)
+
+// ConfigletBuilder is a configlet generator backed by a Python script
+type ConfigletBuilder struct {
+	Key      string             `json:"key,omitempty"`
+	Name     string             `json:"name"`
+	Script   string             `json:"script"`
+	FormList []BuilderFormField `json:"formList"`
+}
+
+// BuilderFormField is an input field presented when a builder is run
+type BuilderFormField struct {
+	FieldID                    string `json:"fieldId"`
+	FieldLabel                 string `json:"fieldLabel"`
+	Type                       string `json:"type"`
+	Value                      string `json:"value"`
+	HelpText                   string `json:"helpText"`
+	Depends                    string `json:"depends"`
+	DataValidation             string `json:"dataValidation"`
+	DataValidationErrorMessage string `json:"dataValidationErrorMessage"`
+	Validation                 struct {
+		Mandatory bool `json:"mandatory"`
+	} `json:"validation"`
+}
+
+type builderData struct {
+	MainScript struct {
+		Data string `json:"data"`
+	} `json:"main_script"`
+	FormList []BuilderFormField `json:"formList"`
+}
+
+type builderRequest struct {
+	Name           string      `json:"name"`
+	WaitForTaskIds bool        `json:"waitForTaskIds,omitempty"`
+	Data           builderData `json:"data"`
+}
+
+type builderResponse struct {
+	Data struct {
+		Key  string `json:"key"`
+		Name string `json:"name"`
+		builderData
+	} `json:"data"`
+	TaskIds      []string `json:"taskIds"`
+	ErrorCode    string   `json:"errorCode"`
+	ErrorMessage string   `json:"errorMessage"`
+}
+
+// GeneratedConfiglet is a configlet produced by running a builder for a device
+type GeneratedConfiglet struct {
+	NetElementID string    `json:"netElementId"`
+	Configlet    Configlet `json:"configlet"`
+}
+
+func newBuilderRequest(builder ConfigletBuilder) builderRequest {
+	req := builderRequest{Name: builder.Name}
+	req.Data.MainScript.Data = builder.Script
+	req.Data.FormList = builder.FormList
+	if req.Data.FormList == nil {
+		req.Data.FormList = []BuilderFormField{}
+	}
+	return req
+}
+
+func (c *CvpClient) builderCall(ctx context.Context, obj interface{}, url string) (builderResponse, error) {
+	body := builderResponse{}
+	resp, err := c.CallWithContext(ctx, obj, url)
+	if err != nil {
+		return body, err
+	}
+	if err = json.Unmarshal(resp, &body); err != nil {
+		log.Printf("Error decoding configlet builder response %+v", err)
+		return body, err
+	}
+	return body, checkErrors(JsonData{ErrorCode: body.ErrorCode, ErrorMessage: body.ErrorMessage})
+}
+
+// AddConfigletBuilder creates a new configlet builder and returns its key
+func (c *CvpClient) AddConfigletBuilder(ctx context.Context, builder ConfigletBuilder) (string, error) {
+	url := "/configlet/addConfigletBuilder.do?isDraft=false"
+	body, err := c.builderCall(ctx, newBuilderRequest(builder), url)
+	if err != nil {
+		return "", err
+	}
+	return body.Data.Key, nil
+}
+
+// UpdateConfigletBuilder replaces the script and form of the builder
+// identified by builder.Key and returns the IDs of any generated tasks
+func (c *CvpClient) UpdateConfigletBuilder(ctx context.Context, builder ConfigletBuilder) ([]string, error) {
+	url := "/configlet/updateConfigletBuilder.do?isDraft=false&action=save&id=" + url.QueryEscape(builder.Key)
+	req := newBuilderRequest(builder)
+	req.WaitForTaskIds = true
+	body, err := c.builderCall(ctx, req, url)
+	if err != nil {
+		return nil, err
+	}
+	return body.TaskIds, nil
+}
+
+// GetConfigletBuilder returns the builder with the given key
+func (c *CvpClient) GetConfigletBuilder(ctx context.Context, key string) (ConfigletBuilder, error) {
+	getBuilderURL := "/configlet/getConfigletBuilder.do?type=&id=" + url.QueryEscape(key)
+	builder := ConfigletBuilder{}
+	respbody, err := c.GetWithContext(ctx, getBuilderURL)
+	if err != nil {
+		return builder, err
+	}
+	body := builderResponse{}
+	if err = json.Unmarshal(respbody, &body); err != nil {
+		log.Printf("Error decoding GetConfigletBuilder :%s\n", err)
+		return builder, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: body.ErrorCode, ErrorMessage: body.ErrorMessage}); err != nil {
+		return builder, err
+	}
+	builder.Key = body.Data.Key
+	if builder.Key == "" {
+		builder.Key = key
+	}
+	builder.Name = body.Data.Name
+	builder.Script = body.Data.MainScript.Data
+	builder.FormList = body.Data.FormList
+	return builder, nil
+}
+
+// GetConfigletBuilderByName returns the builder with exactly the given name
+func (c *CvpClient) GetConfigletBuilderByName(ctx context.Context, name string) (ConfigletBuilder, error) {
+	builders, err := c.ListConfigletBuilders(ctx, name)
+	if err != nil {
+		return ConfigletBuilder{}, err
+	}
+	for _, b := range builders {
+		if b.Name == name {
+			return c.GetConfigletBuilder(ctx, b.Key)
+		}
+	}
+	return ConfigletBuilder{}, fmt.Errorf("No configlet builder named \"%s\" found", name)
+}
+
+// ListConfigletBuilders returns the names and keys of all builders matching
+// query, use GetConfigletBuilder to retrieve the script and form of one
+func (c *CvpClient) ListConfigletBuilders(ctx context.Context, query string) ([]ConfigletBuilder, error) {
+	cfglets, _, err := c.ListConfiglets(ctx, ConfigletFilter{Type: ConfigletTypeBuilder, Query: query})
+	if err != nil {
+		return nil, err
+	}
+	builders := make([]ConfigletBuilder, 0, len(cfglets))
+	for _, cfglet := range cfglets {
+		builders = append(builders, ConfigletBuilder{Key: cfglet.Key, Name: cfglet.Name})
+	}
+	return builders, nil
+}
+
+// DeleteConfigletBuilder deletes the builder with the given name
+func (c *CvpClient) DeleteConfigletBuilder(ctx context.Context, name string) error {
+	builder, err := c.GetConfigletBuilderByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	url := "/configlet/deleteConfiglet.do"
+	body := []DeleteConfiglet{{Key: builder.Key, Name: builder.Name}}
+	resp, err := c.CallWithContext(ctx, body, url)
+	if err != nil {
+		return err
+	}
+	responseBody := JsonData{}
+	if err = json.Unmarshal(resp, &responseBody); err != nil {
+		log.Printf("Error deleting configlet builder %+v", err)
+		return err
+	}
+	return checkErrors(responseBody)
+}
+
+// GenerateConfiglets runs the builder identified by builderKey. When
+// containerID is set configlets are generated in the context of that
+// container, otherwise for the devices in netElementIDs. Form values are
+// passed to the builder keyed by their field ID.
+func (c *CvpClient) GenerateConfiglets(ctx context.Context, builderKey, containerID string, netElementIDs []string, form map[string]string) ([]GeneratedConfiglet, error) {
+	url := "/configlet/autoConfigletGenerator.do"
+	type previewValue struct {
+		FieldID string `json:"fieldId"`
+		Value   string `json:"value"`
+	}
+	req := struct {
+		NetElementIds      []string       `json:"netElementIds"`
+		ConfigletBuilderID string         `json:"configletBuilderId"`
+		ContainerID        string         `json:"containerId"`
+		PageType           string         `json:"pageType"`
+		PreviewValues      []previewValue `json:"previewValues"`
+	}{
+		NetElementIds:      netElementIDs,
+		ConfigletBuilderID: builderKey,
+		ContainerID:        containerID,
+		PageType:           "netelement",
+		PreviewValues:      []previewValue{},
+	}
+	if containerID != "" {
+		req.PageType = "container"
+	}
+	if req.NetElementIds == nil {
+		req.NetElementIds = []string{}
+	}
+	// sorted so that repeated runs send identical requests
+	fields := make([]string, 0, len(form))
+	for field := range form {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		req.PreviewValues = append(req.PreviewValues, previewValue{FieldID: field, Value: form[field]})
+	}
+	resp, err := c.CallWithContext(ctx, req, url)
+	if err != nil {
+		return nil, err
+	}
+	body := struct {
+		Data         []GeneratedConfiglet `json:"data"`
+		ErrorCode    string               `json:"errorCode"`
+		ErrorMessage string               `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(resp, &body); err != nil {
+		log.Printf("Error decoding GenerateConfiglets :%s\n", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: body.ErrorCode, ErrorMessage: body.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	return body.Data, nil
+}
+
+// GetConfigletByContainerID gets list of configlets and builders assigned to
+// a container
+func (c *CvpClient) GetConfigletByContainerID(ctx context.Context, containerID string) ([]Configlet, error) {
+	url := "/provisioning/getConfigletsByContainerId.do?containerId=" + url.QueryEscape(containerID) + "&queryParam=&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	respConfiglet := ConfigletList{}
+	if err = json.Unmarshal(respbody, &respConfiglet); err != nil {
+		log.Printf("Error decoding GetConfigletByContainerID :%s\n", err)
+		return nil, err
+	}
+	return respConfiglet.List, nil
+}
+
+// ApplyConfigletBuilderToContainer assigns builders to a container, keeping
+// the configlets and builders that are already assigned to it. With save
+// set, a failure discards every unsaved temp action, see CancelTopology.
+// Without it the session is left as it is.
+func (c *CvpClient) ApplyConfigletBuilderToContainer(ctx context.Context, containerName string, builderNames []string, save bool) (sdata SaveData, err error) {
+	container, err := c.GetContainerByName(containerName)
+	if err != nil {
+		return sdata, err
+	}
+	current, err := c.GetConfigletByContainerID(ctx, container.Key)
+	if err != nil {
+		log.Printf("Error retrieving configlets from a container")
+		return sdata, err
+	}
+	var cfglets, builders []Configlet
+	for _, cfglet := range current {
+		if cfglet.Type == ConfigletTypeBuilder {
+			builders = append(builders, cfglet)
+		} else {
+			cfglets = append(cfglets, cfglet)
+		}
+	}
+	var newBuilders []Configlet
+	for _, name := range builderNames {
+		builder, err := c.GetConfigletBuilderByName(ctx, name)
+		if err != nil {
+			return sdata, err
+		}
+		newBuilders = append(newBuilders, Configlet{Key: builder.Key, Name: builder.Name})
+	}
+	builders = c.mergeCfglet(builders, newBuilders)
+	applyBuilder := Action{
+		Info:                            "Configlet Builder Assign to container: " + containerName,
+		InfoPreview:                     "<b>Configlet Builder assign</b> to Container " + containerName,
+		Action:                          "associate",
+		NodeType:                        "configlet",
+		NodeID:                          "",
+		ToID:                            container.Key,
+		ToIDType:                        "container",
+		ToName:                          containerName,
+		ConfigletList:                   getKeys(cfglets),
+		ConfigletNamesList:              getNames(cfglets),
+		ConfigletBuilderList:            getKeys(builders),
+		ConfigletBuilderNamesList:       getNames(builders),
+		IgnoreConfigletList:             []string{},
+		IgnoreConfigletNamesList:        []string{},
+		IgnoreConfigletBuilderList:      []string{},
+		IgnoreConfigletBuilderNamesList: []string{},
+	}
+	log.Printf("Applying configlet builder : %+v", applyBuilder)
+	if err = c.addTempAction(ctx, applyBuilder); err != nil {
+		if save {
+			err = c.rollback(ctx, err)
+		}
+		return sdata, err
+	}
+	if save {
+		if sdata, err = c.saveTopologyV2(ctx, []string{}); err != nil {
+			return sdata, c.rollback(ctx, err)
+		}
+	}
+	return sdata, err
+}
@@ -0,0 +1,72 @@
+package cvpgo
+
+import (
+	"context"
+	"testing"
+)
+
+const testBuilderScript = `from cvplibrary import Form
+print "vlan %s" % Form.getFieldById("vlan").getValue()
+`
+
+func buildBuilderTestData() ConfigletBuilder {
+	field := BuilderFormField{
+		FieldID:    "vlan",
+		FieldLabel: "VLAN",
+		Type:       "Text box",
+	}
+	return ConfigletBuilder{
+		Name:     "TestBuilder",
+		Script:   testBuilderScript,
+		FormList: []BuilderFormField{field},
+	}
+}
+
+func TestAddConfigletBuilder(t *testing.T) {
+	testdata := buildTestData()
+	cvpInfo := *testdata.CVP
+	cvp := New(cvpInfo.IPAddress, cvpInfo.Username, cvpInfo.Password)
+	key, err := cvp.AddConfigletBuilder(context.Background(), buildBuilderTestData())
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+	t.Logf("Created configlet builder with key %s", key)
+}
+
+func TestGenerateConfiglets(t *testing.T) {
+	testdata := buildTestData()
+	cvpInfo := *testdata.CVP
+	cvp := New(cvpInfo.IPAddress, cvpInfo.Username, cvpInfo.Password)
+	ctx := context.Background()
+	builder, err := cvp.GetConfigletBuilderByName(ctx, buildBuilderTestData().Name)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	form := map[string]string{"vlan": "100"}
+	generated, err := cvp.GenerateConfiglets(ctx, builder.Key, "", []string{testdata.DeviceMAC}, form)
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+	t.Logf("Generated %+v", generated)
+}
+
+func TestApplyConfigletBuilderToContainer(t *testing.T) {
+	testdata := buildTestData()
+	cvpInfo := *testdata.CVP
+	cvp := New(cvpInfo.IPAddress, cvpInfo.Username, cvpInfo.Password)
+	builders := []string{buildBuilderTestData().Name}
+	_, err := cvp.ApplyConfigletBuilderToContainer(context.Background(), testdata.DeviceContainer, builders, true)
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+}
+
+func TestDeleteConfigletBuilder(t *testing.T) {
+	testdata := buildTestData()
+	cvpInfo := *testdata.CVP
+	cvp := New(cvpInfo.IPAddress, cvpInfo.Username, cvpInfo.Password)
+	err := cvp.DeleteConfigletBuilder(context.Background(), buildBuilderTestData().Name)
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Change control states, ChangeControl.Status holds one of them
+const (
+	ChangeControlPending    = "Pending"
+	ChangeControlInProgress = "Inprogress"
+	ChangeControlCompleted  = "Completed"
+	ChangeControlFailed     = "Failed"
+	ChangeControlCancelled  = "Cancelled"
+)
+
+// ChangeControl is a CVP change control grouping tasks for approval and
+// ordered execution
+type ChangeControl struct {
+	ID                string
+	Name              string
+	Status            string
+	CreatedBy         string
+	CreatedTimestamp  int64
+	Approved          bool
+	ExecutedBy        string
+	ExecutedTimestamp int64
+	// Error is the reason CVP gives for a failed change control
+	Error     string
+	TaskCount int
+	// Stages holds the task IDs of the change control in execution order,
+	// tasks within a stage run in parallel
+	Stages [][]string
+}
+
+// TaskIds returns the IDs of all tasks of the change control
+func (cc ChangeControl) TaskIds() []string {
+	var ids []string
+	for _, stage := range cc.Stages {
+		ids = append(ids, stage...)
+	}
+	return ids
+}
+
+// ChangeControlStage is a group of tasks in a change control. Stages run
+// one after the other, the tasks of a stage run in parallel when Parallel is
+// set and in the given order otherwise.
+type ChangeControlStage struct {
+	TaskIds  []string
+	Parallel bool
+}
+
+// The change control lifecycle goes through CVP's resource API, the only
+// API generation that supports approvals
+const (
+	changeControlPath       = "/api/resources/changecontrol/v1/ChangeControl"
+	changeControlConfigPath = "/api/resources/changecontrol/v1/ChangeControlConfig"
+	changeControlRootStage  = "root"
+)
+
+// changeControlStageValue is a stage of a change control in the resource
+// API, it either runs a task or holds rows of stages. Rows run one after
+// the other, the stages of a row in parallel.
+type changeControlStageValue struct {
+	Name   string `json:"name"`
+	Action *struct {
+		Name string `json:"name"`
+		Args struct {
+			Values map[string]string `json:"values"`
+		} `json:"args"`
+	} `json:"action,omitempty"`
+	Rows *struct {
+		Values []struct {
+			Values []string `json:"values"`
+		} `json:"values"`
+	} `json:"rows,omitempty"`
+}
+
+// changeControlValue is a change control as the resource API returns it
+type changeControlValue struct {
+	Key struct {
+		ID string `json:"id"`
+	} `json:"key"`
+	Change struct {
+		Name        string `json:"name"`
+		RootStageID string `json:"rootStageId"`
+		Stages      struct {
+			Values map[string]changeControlStageValue `json:"values"`
+		} `json:"stages"`
+		Time string `json:"time"`
+		User string `json:"user"`
+	} `json:"change"`
+	Approve struct {
+		Value bool `json:"value"`
+	} `json:"approve"`
+	Start struct {
+		Value bool   `json:"value"`
+		Time  string `json:"time"`
+		User  string `json:"user"`
+	} `json:"start"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+func (v changeControlValue) changeControl() ChangeControl {
+	cc := ChangeControl{
+		ID:                v.Key.ID,
+		Name:              v.Change.Name,
+		CreatedBy:         v.Change.User,
+		CreatedTimestamp:  resourceTimestamp(v.Change.Time),
+		Approved:          v.Approve.Value,
+		ExecutedBy:        v.Start.User,
+		ExecutedTimestamp: resourceTimestamp(v.Start.Time),
+		Error:             v.Error,
+	}
+	switch {
+	case v.Status == "CHANGE_CONTROL_STATUS_RUNNING":
+		cc.Status = ChangeControlInProgress
+	case v.Status != "CHANGE_CONTROL_STATUS_COMPLETED":
+		cc.Status = ChangeControlPending
+	case !v.Start.Value:
+		cc.Status = ChangeControlCancelled
+	case v.Error != "":
+		cc.Status = ChangeControlFailed
+	default:
+		cc.Status = ChangeControlCompleted
+	}
+	if root, ok := v.Change.Stages.Values[v.Change.RootStageID]; ok && root.Rows != nil {
+		for _, row := range root.Rows.Values {
+			var stage []string
+			for _, id := range row.Values {
+				stage = v.appendTasks(stage, id, 0)
+			}
+			cc.Stages = append(cc.Stages, stage)
+		}
+	}
+	cc.TaskCount = len(cc.TaskIds())
+	return cc
+}
+
+// appendTasks appends the IDs of the tasks run by a stage and the stages
+// it holds, depth guards against stages holding themselves
+func (v changeControlValue) appendTasks(ids []string, stageID string, depth int) []string {
+	stage, ok := v.Change.Stages.Values[stageID]
+	if !ok || depth > len(v.Change.Stages.Values) {
+		return ids
+	}
+	if stage.Action != nil && stage.Action.Name == "task" {
+		ids = append(ids, stage.Action.Args.Values["TaskID"])
+	}
+	if stage.Rows != nil {
+		for _, row := range stage.Rows.Values {
+			for _, id := range row.Values {
+				ids = v.appendTasks(ids, id, depth+1)
+			}
+		}
+	}
+	return ids
+}
+
+// resourceTimestamp converts a time of the resource API to milliseconds
+// since the epoch, the unit the rest of CVP's API uses
+func resourceTimestamp(s string) int64 {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// CreateChangeControl creates a change control running the given stages in
+// order and returns its ID. A failed stage stops the stages after it, the
+// tasks of a parallel stage all run.
+func (c *CvpClient) CreateChangeControl(ctx context.Context, name string, stages []ChangeControlStage) (string, error) {
+	ccID := newResourceID()
+	values := make(map[string]interface{})
+	var rows []interface{}
+	addTask := func(taskID string) string {
+		stageID := fmt.Sprintf("%s-%d", ccID, len(values)+1)
+		values[stageID] = map[string]interface{}{
+			"name": "Task " + taskID,
+			"action": map[string]interface{}{
+				"name": "task",
+				"args": map[string]interface{}{"values": map[string]string{"TaskID": taskID}},
+			},
+		}
+		return stageID
+	}
+	for _, stage := range stages {
+		var row []string
+		for _, id := range stage.TaskIds {
+			if stage.Parallel {
+				row = append(row, addTask(id))
+				continue
+			}
+			rows = append(rows, map[string][]string{"values": {addTask(id)}})
+		}
+		if len(row) > 0 {
+			rows = append(rows, map[string][]string{"values": row})
+		}
+	}
+	values[changeControlRootStage] = map[string]interface{}{
+		"name": name,
+		"rows": map[string]interface{}{"values": rows},
+	}
+	data := map[string]interface{}{
+		"key": map[string]string{"id": ccID},
+		"change": map[string]interface{}{
+			"name":        name,
+			"rootStageId": changeControlRootStage,
+			"stages":      map[string]interface{}{"values": values},
+			"notes":       "",
+		},
+	}
+	if err := c.resourcePost(ctx, changeControlConfigPath, data); err != nil {
+		return "", err
+	}
+	return ccID, nil
+}
+
+// GetChangeControl returns the change control with the given ID including
+// its task stages
+func (c *CvpClient) GetChangeControl(ctx context.Context, ccID string) (ChangeControl, error) {
+	value, err := c.changeControlValue(ctx, ccID)
+	if err != nil {
+		return ChangeControl{}, err
+	}
+	return value.changeControl(), nil
+}
+
+func (c *CvpClient) changeControlValue(ctx context.Context, ccID string) (changeControlValue, error) {
+	respbody, err := c.get(ctx, c.resourceURL(changeControlPath+"?key.id="+url.QueryEscape(ccID)))
+	if err != nil {
+		return changeControlValue{}, err
+	}
+	if err = checkResourceError(respbody); err != nil {
+		return changeControlValue{}, err
+	}
+	resp := struct {
+		Value changeControlValue `json:"value"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding GetChangeControl :%s\n", err)
+		return changeControlValue{}, err
+	}
+	if resp.Value.Key.ID == "" {
+		return changeControlValue{}, fmt.Errorf("No change control with ID %s found", ccID)
+	}
+	return resp.Value, nil
+}
+
+// ListChangeControls returns all change controls whose name or ID contains
+// query, an empty query returns all change controls
+func (c *CvpClient) ListChangeControls(ctx context.Context, query string) ([]ChangeControl, error) {
+	var result []ChangeControl
+	err := c.resourceGetAll(ctx, changeControlPath+"/all", func(raw json.RawMessage) error {
+		value := changeControlValue{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			log.Printf("Error decoding ListChangeControls :%s\n", err)
+			return err
+		}
+		cc := value.changeControl()
+		if strings.Contains(cc.Name, query) || strings.Contains(cc.ID, query) {
+			result = append(result, cc)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// ApproveChangeControl approves a change control so that it can be started.
+// The approval applies to the current version of the change control.
+func (c *CvpClient) ApproveChangeControl(ctx context.Context, ccID string) error {
+	value, err := c.changeControlValue(ctx, ccID)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"key":     map[string]string{"id": ccID},
+		"approve": map[string]interface{}{"value": true, "notes": ""},
+		"version": value.Change.Time,
+	}
+	return c.resourcePost(ctx, "/api/resources/changecontrol/v1/ApproveConfig", data)
+}
+
+// StartChangeControl executes an approved change control immediately
+func (c *CvpClient) StartChangeControl(ctx context.Context, ccID string) error {
+	return c.setChangeControlStart(ctx, ccID, true)
+}
+
+// StopChangeControl cancels a change control, tasks that have already run
+// are not rolled back
+func (c *CvpClient) StopChangeControl(ctx context.Context, ccID string) error {
+	return c.setChangeControlStart(ctx, ccID, false)
+}
+
+func (c *CvpClient) setChangeControlStart(ctx context.Context, ccID string, start bool) error {
+	data := map[string]interface{}{
+		"key":   map[string]string{"id": ccID},
+		"start": map[string]interface{}{"value": start, "notes": ""},
+	}
+	return c.resourcePost(ctx, changeControlConfigPath, data)
+}
+
+// DeleteChangeControl deletes a change control, its tasks are kept
+func (c *CvpClient) DeleteChangeControl(ctx context.Context, ccID string) error {
+	return c.resourceDelete(ctx, changeControlConfigPath+"?key.id="+url.QueryEscape(ccID))
+}
+
+// WaitForChangeControl waits for the tasks of a started change control
+// using WaitForTasks and returns the change control in its final state
+// together with the result of every task
+func (c *CvpClient) WaitForChangeControl(ctx context.Context, ccID string, opts WaitOptions) (ChangeControl, map[string]TaskResult, error) {
+	cc, err := c.GetChangeControl(ctx, ccID)
+	if err != nil {
+		return cc, nil, err
+	}
+	results, err := c.WaitForTasks(ctx, cc.TaskIds(), opts)
+	if err != nil {
+		return cc, results, err
+	}
+	if cc, err = c.GetChangeControl(ctx, ccID); err != nil {
+		return cc, results, err
+	}
+	if cc.Status == ChangeControlFailed || cc.Status == ChangeControlCancelled {
+		return cc, results, fmt.Errorf("Change control %s is %s", ccID, cc.Status)
+	}
+	return cc, results, nil
+}
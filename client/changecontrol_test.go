@@ -0,0 +1,106 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChangeControlLifecycle(t *testing.T) {
+	data := buildConfigletTestData()
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	ctx := context.Background()
+	tasks, err := cvp.ListTasks(ctx, TaskFilter{State: "Pending"})
+	if err != nil || len(tasks) == 0 {
+		t.Skipf("No pending tasks to work with : %v", err)
+	}
+	stage := ChangeControlStage{Parallel: true}
+	for _, task := range tasks {
+		stage.TaskIds = append(stage.TaskIds, task.ID)
+	}
+	ccID, err := cvp.CreateChangeControl(ctx, "cvpgo-test", []ChangeControlStage{stage})
+	if err != nil {
+		t.Fatalf("Error creating change control : %s", err)
+	}
+	cc, err := cvp.GetChangeControl(ctx, ccID)
+	if err != nil {
+		t.Errorf("Error getting change control : %s", err)
+	}
+	if len(cc.Stages) != 1 || len(cc.TaskIds()) != len(stage.TaskIds) {
+		t.Errorf("Unexpected change control stages %+v", cc.Stages)
+	}
+	if err = cvp.ApproveChangeControl(ctx, ccID); err != nil {
+		t.Errorf("Error approving change control : %s", err)
+	}
+	if err = cvp.StartChangeControl(ctx, ccID); err != nil {
+		t.Fatalf("Error starting change control : %s", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	if _, _, err = cvp.WaitForChangeControl(waitCtx, ccID, WaitOptions{Interval: 2 * time.Second}); err != nil {
+		t.Errorf("Error waiting for change control : %s", err)
+	}
+}
+
+func TestApproveAndStartChangeControl(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/resources/changecontrol/v1/ChangeControl":
+			if r.URL.Query().Get("key.id") != "cc1" {
+				fmt.Fprint(w, `{"code":5,"message":"resource not found"}`)
+				return
+			}
+			fmt.Fprint(w, `{"value":{"key":{"id":"cc1"},"change":{"name":"upgrade","rootStageId":"root","stages":{"values":{
+				"root":{"name":"upgrade","rows":{"values":[{"values":["s1","s2"]},{"values":["s3"]}]}},
+				"s1":{"name":"Task 1","action":{"name":"task","args":{"values":{"TaskID":"1"}}}},
+				"s2":{"name":"Task 2","action":{"name":"task","args":{"values":{"TaskID":"2"}}}},
+				"s3":{"name":"Task 3","action":{"name":"task","args":{"values":{"TaskID":"3"}}}}}},
+				"time":"2021-05-04T10:00:00.5Z","user":"cvpadmin"},"status":"CHANGE_CONTROL_STATUS_NOT_STARTED"}}`)
+		case "POST /api/resources/changecontrol/v1/ApproveConfig", "POST /api/resources/changecontrol/v1/ChangeControlConfig":
+			body := map[string]interface{}{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("Error decoding request : %s", err)
+			}
+			if body["version"] != nil && body["version"] != "2021-05-04T10:00:00.5Z" {
+				t.Errorf("Approval of version %v, want the change control's time", body["version"])
+			}
+			if start, ok := body["start"].(map[string]interface{}); ok && start["value"] == true {
+				calls = append(calls, "start")
+			} else {
+				calls = append(calls, "approve")
+			}
+			fmt.Fprint(w, `{"value":{"key":{"id":"cc1"}},"time":"2021-05-04T10:01:00Z"}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL + "/cvpservice", Client: srv.Client()}
+	ctx := context.Background()
+
+	cc, err := cvp.GetChangeControl(ctx, "cc1")
+	if err != nil {
+		t.Fatalf("Error getting change control : %s", err)
+	}
+	if cc.Status != ChangeControlPending || fmt.Sprint(cc.Stages) != "[[1 2] [3]]" || cc.TaskCount != 3 {
+		t.Errorf("Unexpected change control %+v", cc)
+	}
+	if err = cvp.ApproveChangeControl(ctx, "cc1"); err != nil {
+		t.Fatalf("Error approving change control : %s", err)
+	}
+	if err = cvp.StartChangeControl(ctx, "cc1"); err != nil {
+		t.Fatalf("Error starting change control : %s", err)
+	}
+	if fmt.Sprint(calls) != "[approve start]" {
+		t.Errorf("Unexpected calls %v", calls)
+	}
+	if err = cvp.ApproveChangeControl(ctx, "cc2"); err == nil {
+		t.Errorf("Expected an error approving a missing change control")
+	}
+}
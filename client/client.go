@@ -2,12 +2,16 @@ package cvpgo
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"strings"
 )
 
 // CvpClient provides a client to a CVP Host
@@ -19,8 +23,14 @@ type CvpClient struct {
 
 // User defines a CVP user
 type User struct {
-	UserID   string `json:"userId"`
-	Password string `json:"password"`
+	UserID        string `json:"userId"`
+	Password      string `json:"password,omitempty"`
+	Email         string `json:"email,omitempty"`
+	FirstName     string `json:"firstName,omitempty"`
+	LastName      string `json:"lastName,omitempty"`
+	ContactNumber string `json:"contactNumber,omitempty"`
+	UserStatus    string `json:"userStatus,omitempty"`
+	UserType      string `json:"userType,omitempty"`
 }
 
 type authResp struct {
@@ -63,11 +73,30 @@ func (c *CvpClient) authenticate(username string, password string) {
 
 // Call issues a POST to the svcurl with a JSON encoded obj
 func (c *CvpClient) Call(obj interface{}, svcurl string) ([]byte, error) {
+	return c.CallWithContext(context.Background(), obj, svcurl)
+}
+
+// CallWithContext issues a POST to the svcurl with a JSON encoded obj,
+// aborting the request when ctx is done
+func (c *CvpClient) CallWithContext(ctx context.Context, obj interface{}, svcurl string) ([]byte, error) {
+	return c.post(ctx, obj, c.BaseURL+svcurl)
+}
+
+// resourceURL returns the URL of a path in CVP's resource API, which is
+// served next to the cvpservice API rather than below it
+func (c *CvpClient) resourceURL(path string) string {
+	return strings.TrimSuffix(c.BaseURL, "/cvpservice") + path
+}
+
+func (c *CvpClient) post(ctx context.Context, obj interface{}, url string) ([]byte, error) {
 	jsonValue, err := json.Marshal(obj)
 	log.Printf("Calling POST with JSON: %s", jsonValue)
-	url := c.BaseURL + svcurl
 	log.Printf("Target URL is : %s", url)
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonValue))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
 	for _, c := range c.Cookies {
 		req.AddCookie(c)
 	}
@@ -76,6 +105,7 @@ func (c *CvpClient) Call(obj interface{}, svcurl string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -86,8 +116,26 @@ func (c *CvpClient) Call(obj interface{}, svcurl string) ([]byte, error) {
 
 // Get issues a HTTP GET to the specified CVP service and returns the data
 func (c *CvpClient) Get(svcurl string) ([]byte, error) {
-	url := c.BaseURL + svcurl
-	req, err := http.NewRequest("GET", url, nil)
+	return c.GetWithContext(context.Background(), svcurl)
+}
+
+// GetWithContext issues a HTTP GET to the specified CVP service and returns
+// the data, aborting the request when ctx is done
+func (c *CvpClient) GetWithContext(ctx context.Context, svcurl string) ([]byte, error) {
+	return c.get(ctx, c.BaseURL+svcurl)
+}
+
+func (c *CvpClient) get(ctx context.Context, url string) ([]byte, error) {
+	return c.do(ctx, "GET", url)
+}
+
+// do issues a request without a body, such as a GET or DELETE
+func (c *CvpClient) do(ctx context.Context, method, url string) ([]byte, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
 	for _, c := range c.Cookies {
 		req.AddCookie(c)
 	}
@@ -96,6 +144,7 @@ func (c *CvpClient) Get(svcurl string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -103,3 +152,39 @@ func (c *CvpClient) Get(svcurl string) ([]byte, error) {
 	// fmt.Println("response Body:", string(body))
 	return body, nil
 }
+
+// Upload issues a multipart POST to the svcurl, sending the data read from r
+// as a file with the given name
+func (c *CvpClient) Upload(ctx context.Context, svcurl, fileName string, r io.Reader) ([]byte, error) {
+	url := c.BaseURL + svcurl
+	log.Printf("Uploading %s to : %s", fileName, url)
+	// stream the file instead of buffering it, images are large
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		part, err := form.CreateFormFile("file", fileName)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	req, err := http.NewRequest("POST", url, pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for _, c := range c.Cookies {
+		req.AddCookie(c)
+	}
+	req.Header.Add("Content-Type", form.FormDataContentType())
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
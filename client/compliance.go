@@ -0,0 +1,328 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// ComplianceCodes describes the compliance codes CVP reports for devices
+var ComplianceCodes = map[string]string{
+	"0000": "Compliant",
+	"0001": "Config Out of Sync",
+	"0002": "Image Out of Sync",
+	"0003": "Config & Image Out of Sync",
+	"0004": "Config, Image and Extension Out of Sync",
+	"0005": "Device Not Reachable",
+	"0006": "The current EOS version on this device is not supported",
+	"0007": "Extensions Out of Sync",
+	"0008": "Config, Image and Extensions Out of Sync",
+	"0009": "Config and Extensions Out of Sync",
+}
+
+// complianceFlags is what a compliance code reports as out of sync
+type complianceFlags struct {
+	config, image, extensions bool
+}
+
+// complianceCodeFlags holds the codes reporting a device in or out of sync,
+// the other codes report devices that cannot be checked
+var complianceCodeFlags = map[string]complianceFlags{
+	"0000": {},
+	"0001": {config: true},
+	"0002": {image: true},
+	"0003": {config: true, image: true},
+	"0004": {config: true, image: true, extensions: true},
+	"0007": {extensions: true},
+	"0008": {config: true, image: true, extensions: true},
+	"0009": {config: true, extensions: true},
+}
+
+// complianceCode returns code with its config part replaced, CVP has no
+// code for only the image and extensions out of sync so code is kept then
+func complianceCode(code string, configOutOfSync bool) string {
+	flags := complianceCodeFlags[code]
+	flags.config = configOutOfSync
+	for _, c := range []string{"0000", "0001", "0002", "0003", "0004", "0007", "0009"} {
+		if complianceCodeFlags[c] == flags {
+			return c
+		}
+	}
+	return code
+}
+
+// Compliance states of a device in a compliance report
+const (
+	ComplianceInSync    = "in-sync"
+	ComplianceOutOfSync = "out-of-sync"
+	ComplianceError     = "error"
+)
+
+// Exit codes returned by ComplianceResult.ExitCode
+const (
+	ComplianceExitOK        = 0
+	ComplianceExitOutOfSync = 1
+	ComplianceExitError     = 2
+)
+
+// defaultComplianceConcurrency is the number of devices compared at a time
+// when ComplianceScope.Concurrency is not set
+const defaultComplianceConcurrency = 4
+
+// ComplianceScope selects the devices of a compliance report, those in
+// Container and its child containers or the whole inventory when it is
+// empty
+type ComplianceScope struct {
+	Container   string
+	Concurrency int
+}
+
+// DeviceCompliance is the compliance of one device. New, Mismatch and
+// Reconcile count the designed config lines missing from the device, the
+// lines that differ and the running config lines missing from the design.
+// Code is the CVP compliance code of the device with its config part taken
+// from the comparison, the image and extension parts are CVP's. It is empty
+// when the comparison failed.
+type DeviceCompliance struct {
+	DeviceID  string `json:"deviceId"`
+	Hostname  string `json:"hostname"`
+	IPAddress string `json:"ipAddress"`
+	Code      string `json:"complianceCode"`
+	Status    string `json:"status"`
+	New       int    `json:"new"`
+	Mismatch  int    `json:"mismatch"`
+	Reconcile int    `json:"reconcile"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Description returns the meaning of the device's CVP compliance code
+func (d DeviceCompliance) Description() string {
+	if desc, ok := ComplianceCodes[d.Code]; ok {
+		return desc
+	}
+	if d.Code == "" {
+		return ""
+	}
+	return "Unknown (" + d.Code + ")"
+}
+
+// ComplianceResult is the compliance of every device in a scope
+type ComplianceResult struct {
+	Scope     string             `json:"scope"`
+	Generated time.Time          `json:"generated"`
+	Devices   []DeviceCompliance `json:"devices"`
+	// Codes counts the devices by CVP compliance code
+	Codes     map[string]int `json:"codes"`
+	InSync    int            `json:"inSync"`
+	OutOfSync int            `json:"outOfSync"`
+	Errors    int            `json:"errors"`
+}
+
+// ComplianceReport compares the designed config of every device in scope
+// against its running config, as ValidateCompareCfglt does, and reports
+// which devices are out of sync. A device whose comparison fails is
+// reported with ComplianceError rather than failing the report.
+func (c *CvpClient) ComplianceReport(ctx context.Context, scope ComplianceScope) (ComplianceResult, error) {
+	result := ComplianceResult{Scope: scope.Container, Generated: time.Now(), Codes: make(map[string]int)}
+	if result.Scope == "" {
+		result.Scope = "inventory"
+	}
+	devices, err := c.ListDevices(ctx)
+	if err != nil {
+		return result, err
+	}
+	if scope.Container != "" {
+		if devices, err = c.devicesInContainer(ctx, devices, scope.Container); err != nil {
+			return result, err
+		}
+	}
+	concurrency := scope.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultComplianceConcurrency
+	}
+	result.Devices = make([]DeviceCompliance, len(devices))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, dev := range devices {
+		wg.Add(1)
+		go func(i int, dev NetElement) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				result.Devices[i] = newDeviceCompliance(dev)
+				result.Devices[i].setError(ctx.Err())
+				return
+			}
+			defer func() { <-sem }()
+			result.Devices[i] = c.deviceCompliance(ctx, dev)
+		}(i, dev)
+	}
+	wg.Wait()
+	sort.SliceStable(result.Devices, func(i, j int) bool { return result.Devices[i].Hostname < result.Devices[j].Hostname })
+	for _, d := range result.Devices {
+		if d.Code != "" {
+			result.Codes[d.Code]++
+		}
+		switch d.Status {
+		case ComplianceInSync:
+			result.InSync++
+		case ComplianceOutOfSync:
+			result.OutOfSync++
+		default:
+			result.Errors++
+		}
+	}
+	return result, ctx.Err()
+}
+
+// devicesInContainer returns the devices in the named container or any of
+// the containers below it
+func (c *CvpClient) devicesInContainer(ctx context.Context, devices []NetElement, name string) ([]NetElement, error) {
+	containers, err := c.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[string][]Container)
+	var top []Container
+	for _, container := range containers {
+		children[container.ParentName] = append(children[container.ParentName], container)
+		if container.Name == name {
+			top = append(top, container)
+		}
+	}
+	if len(top) == 0 {
+		return nil, fmt.Errorf("No container named \"%s\" found", name)
+	}
+	inScope := make(map[string]bool)
+	for queue := top; len(queue) > 0; queue = queue[1:] {
+		if inScope[queue[0].Key] {
+			continue
+		}
+		inScope[queue[0].Key] = true
+		queue = append(queue, children[queue[0].Name]...)
+	}
+	var inContainer []NetElement
+	for _, dev := range devices {
+		if inScope[dev.ParentContainerKey] {
+			inContainer = append(inContainer, dev)
+		}
+	}
+	return inContainer, nil
+}
+
+func newDeviceCompliance(dev NetElement) DeviceCompliance {
+	return DeviceCompliance{
+		DeviceID:  dev.SystemMacAddress,
+		Hostname:  dev.Fqdn,
+		IPAddress: dev.IPAddress,
+	}
+}
+
+func (d *DeviceCompliance) setError(err error) {
+	d.Status = ComplianceError
+	d.Error = err.Error()
+}
+
+func (c *CvpClient) deviceCompliance(ctx context.Context, dev NetElement) DeviceCompliance {
+	d := newDeviceCompliance(dev)
+	if _, ok := complianceCodeFlags[dev.ComplianceCode]; !ok && dev.ComplianceCode != "" {
+		// unreachable or unsupported devices cannot be compared
+		d.Code = dev.ComplianceCode
+		d.setError(fmt.Errorf("%s", d.Description()))
+		return d
+	}
+	assigned, err := c.GetConfigletByDeviceIDWithContext(ctx, dev.SystemMacAddress)
+	if err != nil {
+		d.setError(err)
+		return d
+	}
+	validation, err := c.validateCompareCfglt(ctx, dev.SystemMacAddress, getKeys(assigned))
+	if err != nil {
+		d.setError(err)
+		return d
+	}
+	if validation.ErrorMsg != "" {
+		d.setError(fmt.Errorf("%s", validation.ErrorMsg))
+		return d
+	}
+	d.New, d.Mismatch, d.Reconcile = validation.New, validation.Mismatch, validation.Reconcile
+	// the config part of the code cached in the inventory may predate the
+	// comparison, its image and extension parts are still CVP's
+	d.Code = complianceCode(dev.ComplianceCode, d.New+d.Mismatch+d.Reconcile > 0)
+	d.Status = ComplianceInSync
+	if d.Code != "0000" {
+		d.Status = ComplianceOutOfSync
+	}
+	return d
+}
+
+// ExitCode returns the exit status for a CI job checking the report:
+// ComplianceExitError if any device could not be checked,
+// ComplianceExitOutOfSync if any device is out of sync and ComplianceExitOK
+// otherwise
+func (r ComplianceResult) ExitCode() int {
+	if r.Errors > 0 {
+		return ComplianceExitError
+	}
+	if r.OutOfSync > 0 {
+		return ComplianceExitOutOfSync
+	}
+	return ComplianceExitOK
+}
+
+// WriteJSON writes the report as indented JSON
+func (r ComplianceResult) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+var complianceColumns = []string{"hostname", "ipAddress", "deviceId", "status", "complianceCode", "description", "new", "mismatch", "reconcile", "error"}
+
+func (d DeviceCompliance) row() []string {
+	return []string{d.Hostname, d.IPAddress, d.DeviceID, d.Status, d.Code, d.Description(),
+		strconv.Itoa(d.New), strconv.Itoa(d.Mismatch), strconv.Itoa(d.Reconcile), d.Error}
+}
+
+// WriteCSV writes one CSV record per device after a header record
+func (r ComplianceResult) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(complianceColumns); err != nil {
+		return err
+	}
+	for _, d := range r.Devices {
+		if err := cw.Write(d.row()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteTable writes the report as an aligned table followed by a summary
+func (r ComplianceResult) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOSTNAME\tIP ADDRESS\tSTATUS\tCODE\tNEW\tMISMATCH\tRECONCILE\tDETAILS")
+	for _, d := range r.Devices {
+		details := d.Description()
+		if d.Error != "" {
+			details = d.Error
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			d.Hostname, d.IPAddress, d.Status, d.Code, d.New, d.Mismatch, d.Reconcile, details)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%s: %d devices, %d in sync, %d out of sync, %d errors\n",
+		r.Scope, len(r.Devices), r.InSync, r.OutOfSync, r.Errors)
+	return err
+}
@@ -0,0 +1,137 @@
+package cvpgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testComplianceResult() ComplianceResult {
+	return ComplianceResult{
+		Scope: "Tenant",
+		Devices: []DeviceCompliance{
+			{Hostname: "leaf1", IPAddress: "10.0.0.1", Code: "0000", Status: ComplianceInSync},
+			{Hostname: "leaf2", IPAddress: "10.0.0.2", Code: "0001", Status: ComplianceOutOfSync, New: 2, Reconcile: 1},
+		},
+		Codes:     map[string]int{"0000": 1, "0001": 1},
+		InSync:    1,
+		OutOfSync: 1,
+	}
+}
+
+func TestComplianceExitCode(t *testing.T) {
+	r := testComplianceResult()
+	if code := r.ExitCode(); code != ComplianceExitOutOfSync {
+		t.Errorf("ExitCode() = %d, want %d", code, ComplianceExitOutOfSync)
+	}
+	r.Errors = 1
+	if code := r.ExitCode(); code != ComplianceExitError {
+		t.Errorf("ExitCode() = %d, want %d", code, ComplianceExitError)
+	}
+	if code := (ComplianceResult{InSync: 3}).ExitCode(); code != ComplianceExitOK {
+		t.Errorf("ExitCode() = %d, want %d", code, ComplianceExitOK)
+	}
+}
+
+func TestComplianceOutput(t *testing.T) {
+	r := testComplianceResult()
+	var b bytes.Buffer
+	if err := r.WriteCSV(&b); err != nil {
+		t.Fatalf("Error writing CSV : %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || lines[2] != "leaf2,10.0.0.2,,out-of-sync,0001,Config Out of Sync,2,0,1," {
+		t.Errorf("Unexpected CSV\n%s", b.String())
+	}
+	b.Reset()
+	if err := r.WriteTable(&b); err != nil {
+		t.Fatalf("Error writing table : %s", err)
+	}
+	if !strings.Contains(b.String(), "Tenant: 2 devices, 1 in sync, 1 out of sync, 0 errors") {
+		t.Errorf("Unexpected table\n%s", b.String())
+	}
+	b.Reset()
+	if err := r.WriteJSON(&b); err != nil {
+		t.Fatalf("Error writing JSON : %s", err)
+	}
+	decoded := ComplianceResult{}
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil || len(decoded.Devices) != 2 || decoded.Codes["0001"] != 1 {
+		t.Errorf("Unexpected JSON %s (%v)", b.String(), err)
+	}
+}
+
+func TestComplianceReport(t *testing.T) {
+	data := buildTestData()
+	cvp := New(data.CVP.IPAddress, data.CVP.Username, data.CVP.Password)
+	result, err := cvp.ComplianceReport(context.Background(), ComplianceScope{Container: data.DeviceContainer})
+	if err != nil {
+		t.Fatalf("Error building compliance report : %s", err)
+	}
+	if len(result.Devices) != result.InSync+result.OutOfSync+result.Errors {
+		t.Errorf("Unexpected compliance summary %+v", result)
+	}
+}
+
+func TestComplianceReportSubtree(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/inventory/getInventory.do":
+			// the comparison decides the config part of the codes, the
+			// image and extension parts and unreachable devices are CVP's
+			fmt.Fprint(w, `{"netElementList":[
+				{"fqdn":"leaf1","systemMacAddress":"m1","parentContainerKey":"c1","complianceCode":"0001"},
+				{"fqdn":"leaf2","systemMacAddress":"m2","parentContainerKey":"c2","complianceCode":"0000"},
+				{"fqdn":"leaf3","systemMacAddress":"m4","parentContainerKey":"c1","complianceCode":"0002"},
+				{"fqdn":"leaf4","systemMacAddress":"m5","parentContainerKey":"c2","complianceCode":"0009"},
+				{"fqdn":"leaf5","systemMacAddress":"m6","parentContainerKey":"c2","complianceCode":"0005"},
+				{"fqdn":"spine1","systemMacAddress":"m3","parentContainerKey":"c3"}]}`)
+		case "/inventory/add/searchContainers.do":
+			fmt.Fprint(w, `{"data":[{"name":"Tenant","key":"root"},{"name":"Leafs","key":"c1","parentName":"Tenant"},
+				{"name":"Rack1","key":"c2","parentName":"Leafs"},{"name":"Spines","key":"c3","parentName":"Tenant"}]}`)
+		case "/provisioning/getConfigletsByNetElementId.do":
+			fmt.Fprint(w, `{"configletList":[]}`)
+		case "/provisioning/v2/validateAndCompareConfiglets.do":
+			req := ValidateRequest{}
+			json.NewDecoder(r.Body).Decode(&req)
+			mismatch := 0
+			if req.NetElementID == "m2" {
+				mismatch = 2
+			}
+			fmt.Fprintf(w, `{"mismatch":%d}`, mismatch)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL, Client: srv.Client()}
+	result, err := cvp.ComplianceReport(context.Background(), ComplianceScope{Container: "Leafs"})
+	if err != nil {
+		t.Fatalf("Error building compliance report : %s", err)
+	}
+	if len(result.Devices) != 5 || result.Devices[0].Hostname != "leaf1" || result.Devices[4].Hostname != "leaf5" {
+		t.Fatalf("Unexpected devices %+v", result.Devices)
+	}
+	if result.InSync != 1 || result.OutOfSync != 3 || result.Errors != 1 {
+		t.Errorf("Unexpected summary %+v", result)
+	}
+	expected := []struct{ code, status string }{
+		{"0000", ComplianceInSync},
+		{"0001", ComplianceOutOfSync},
+		{"0002", ComplianceOutOfSync},
+		{"0007", ComplianceOutOfSync},
+		{"0005", ComplianceError},
+	}
+	for i, want := range expected {
+		if d := result.Devices[i]; d.Code != want.code || d.Status != want.status {
+			t.Errorf("Unexpected compliance of %s: %s %s, want %s %s", d.Hostname, d.Code, d.Status, want.code, want.status)
+		}
+	}
+	if result.Devices[4].Error != "Device Not Reachable" {
+		t.Errorf("Unexpected error %q", result.Devices[4].Error)
+	}
+}
@@ -0,0 +1,162 @@
+package cvpgo
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/fredhsu/cvpgo/eosconfig"
+)
+
+// ConfigletDiff is the local difference between a configlet stored in CVP
+// and a proposed config for it
+type ConfigletDiff struct {
+	Name string
+	// Exists is false when there is no configlet with this name yet
+	Exists bool
+	// Diff is the line diff of the normalised configs
+	Diff ConfigDiff
+	// Sections is the same difference organised by EOS config stanza
+	Sections []SectionDiff
+}
+
+// Unified renders the line diff in unified diff format
+func (d ConfigletDiff) Unified() string {
+	return d.Diff.Unified(3)
+}
+
+// SectionDiff is the difference of one EOS config stanza. Added and
+// Removed hold the lines directly below the stanza, nested stanzas that
+// differ are listed in Children. Op is DiffAdded or DiffRemoved when the
+// whole stanza only exists on one side and DiffChanged otherwise.
+type SectionDiff struct {
+	Section  string
+	Op       DiffOp
+	Added    []string
+	Removed  []string
+	Children []SectionDiff
+}
+
+// DiffConfiglet compares the configlet with the given name against
+// newConfig without changing anything in CVP. Both configs are normalised
+// first so that comments, trailing whitespace and indentation style do not
+// show up as differences.
+func (c *CvpClient) DiffConfiglet(ctx context.Context, name, newConfig string) (ConfigletDiff, error) {
+	current, found, err := c.findConfiglet(ctx, name)
+	if err != nil {
+		return ConfigletDiff{}, err
+	}
+	old := eosconfig.Parse(name, current.Config)
+	new := eosconfig.Parse(name+" (proposed)", newConfig)
+	result := ConfigletDiff{
+		Name:     name,
+		Exists:   found,
+		Diff:     diffLines(old.Name, new.Name, old.Lines(), new.Lines()),
+		Sections: diffSections(old.Root.Children, new.Root.Children),
+	}
+	return result, nil
+}
+
+// FormatSections renders section diffs as an indented tree, prefixing
+// stanzas and lines with + when added, - when removed and ~ when changed
+func FormatSections(sections []SectionDiff) string {
+	var buf bytes.Buffer
+	formatSections(&buf, sections, "")
+	return buf.String()
+}
+
+func formatSections(buf *bytes.Buffer, sections []SectionDiff, indent string) {
+	for _, section := range sections {
+		marker := "~ "
+		switch section.Op {
+		case DiffAdded:
+			marker = "+ "
+		case DiffRemoved:
+			marker = "- "
+		}
+		if section.Section != "" {
+			buf.WriteString(indent + marker + section.Section + "\n")
+		}
+		childIndent := indent
+		if section.Section != "" {
+			childIndent += eosconfig.Indent
+		}
+		for _, line := range section.Removed {
+			buf.WriteString(childIndent + "- " + line + "\n")
+		}
+		for _, line := range section.Added {
+			buf.WriteString(childIndent + "+ " + line + "\n")
+		}
+		formatSections(buf, section.Children, childIndent)
+	}
+}
+
+// diffSections compares two stanza trees. Lines are matched by their text,
+// so a changed line shows up as one removed and one added line.
+func diffSections(old, new []*eosconfig.Node) []SectionDiff {
+	top := diffSection("", old, new)
+	if top == nil {
+		return nil
+	}
+	// lines at the top level of the config are kept in a section without a
+	// header, stanzas are returned as they are
+	var result []SectionDiff
+	if len(top.Added) > 0 || len(top.Removed) > 0 {
+		result = append(result, SectionDiff{Op: DiffChanged, Added: top.Added, Removed: top.Removed})
+	}
+	return append(result, top.Children...)
+}
+
+func diffSection(header string, old, new []*eosconfig.Node) *SectionDiff {
+	diff := SectionDiff{Section: header, Op: DiffChanged}
+	oldByLine := make(map[string]*eosconfig.Node)
+	for _, section := range old {
+		oldByLine[section.Line] = section
+	}
+	newByLine := make(map[string]*eosconfig.Node)
+	for _, section := range new {
+		newByLine[section.Line] = section
+	}
+	for _, section := range new {
+		previous, ok := oldByLine[section.Line]
+		switch {
+		case !ok && len(section.Children) == 0:
+			diff.Added = append(diff.Added, section.Line)
+		case !ok:
+			diff.Children = append(diff.Children, wholeSection(section, DiffAdded))
+		default:
+			if child := diffSection(section.Line, previous.Children, section.Children); child != nil {
+				diff.Children = append(diff.Children, *child)
+			}
+		}
+	}
+	for _, section := range old {
+		if _, ok := newByLine[section.Line]; ok {
+			continue
+		}
+		if len(section.Children) == 0 {
+			diff.Removed = append(diff.Removed, section.Line)
+		} else {
+			diff.Children = append(diff.Children, wholeSection(section, DiffRemoved))
+		}
+	}
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Children) == 0 {
+		return nil
+	}
+	return &diff
+}
+
+// wholeSection describes a stanza that only exists on one side
+func wholeSection(section *eosconfig.Node, op DiffOp) SectionDiff {
+	diff := SectionDiff{Section: section.Line, Op: op}
+	for _, child := range section.Children {
+		switch {
+		case len(child.Children) > 0:
+			diff.Children = append(diff.Children, wholeSection(child, op))
+		case op == DiffAdded:
+			diff.Added = append(diff.Added, child.Line)
+		default:
+			diff.Removed = append(diff.Removed, child.Line)
+		}
+	}
+	return diff
+}
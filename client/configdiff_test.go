@@ -0,0 +1,29 @@
+package cvpgo
+
+import (
+	"testing"
+
+	"github.com/fredhsu/cvpgo/eosconfig"
+)
+
+func TestDiffSections(t *testing.T) {
+	old := eosconfig.Parse("old", "hostname A\ninterface Ethernet1\n   shutdown\ninterface Ethernet2\n   shutdown\nrouter bgp 1\n   address-family ipv4\n      network 10.0.0.0/8\n")
+	new := eosconfig.Parse("new", "hostname B\ninterface Ethernet1\n   description uplink\nrouter bgp 1\n   address-family ipv4\n      network 10.0.0.0/8\n      network 192.168.0.0/16\nvlan 10\n   name users\n")
+	sections := diffSections(old.Root.Children, new.Root.Children)
+	expected := `- hostname A
++ hostname B
+~ interface Ethernet1
+   - shutdown
+   + description uplink
+~ router bgp 1
+   ~ address-family ipv4
+      + network 192.168.0.0/16
++ vlan 10
+   + name users
+- interface Ethernet2
+   - shutdown
+`
+	if got := FormatSections(sections); got != expected {
+		t.Errorf("Unexpected section diff:\n%s", got)
+	}
+}
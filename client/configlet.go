@@ -1,10 +1,12 @@
 package cvpgo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -79,13 +81,91 @@ type Action struct {
 	ConfigletBuilderNamesList       []string `json:"configletBuilderNamesList"`
 	IgnoreConfigletBuilderList      []string `json:"ignoreConfigletBuilderList"`
 	IgnoreConfigletBuilderNamesList []string `json:"ignoreConfigletBuilderNamesList"`
+	IgnoreNodeID                    string   `json:"ignoreNodeId,omitempty"`
+	IgnoreNodeName                  string   `json:"ignoreNodeName,omitempty"`
 }
 
 type Configlet struct {
-	Config     string `json:"config"`
-	Name       string `json:"name"`
-	Key        string `json:"key,omitempty"`
-	Reconciled bool   `json:"reconciled,omitempty"`
+	Config               string `json:"config"`
+	Name                 string `json:"name"`
+	Key                  string `json:"key,omitempty"`
+	Reconciled           bool   `json:"reconciled,omitempty"`
+	Note                 string `json:"note,omitempty"`
+	User                 string `json:"user,omitempty"`
+	Type                 string `json:"type,omitempty"`
+	DateTimeInLongFormat int64  `json:"dateTimeInLongFormat,omitempty"`
+}
+
+// Configlet types accepted by ListConfiglets
+const (
+	ConfigletTypeStatic    = "Configlet"
+	ConfigletTypeBuilder   = "Builder"
+	ConfigletTypeGenerated = "Generated"
+	ConfigletTypeDraft     = "Draft"
+)
+
+// LastModified returns the time the configlet was last changed in CVP
+func (cfglet Configlet) LastModified() time.Time {
+	return time.Unix(0, cfglet.DateTimeInLongFormat*int64(time.Millisecond))
+}
+
+// IsStatic reports whether the configlet is a static configlet rather than
+// a builder or a configlet generated by one. Assigned configlets carry
+// "Static" as their type, not the ConfigletTypeStatic used in queries.
+func (cfglet Configlet) IsStatic() bool {
+	return cfglet.Type != ConfigletTypeBuilder && cfglet.Type != ConfigletTypeGenerated
+}
+
+// ConfigletFilter selects which configlets ListConfiglets returns.
+// Start and End are CVP's startIndex and endIndex, an End of 0 returns all.
+type ConfigletFilter struct {
+	Type  string
+	Query string
+	Start int
+	End   int
+}
+
+type ConfigletListData struct {
+	Total        int         `json:"total"`
+	Data         []Configlet `json:"data"`
+	ErrorCode    string      `json:"errorCode"`
+	ErrorMessage string      `json:"errorMessage"`
+}
+
+// UpsertResult reports what UpsertConfiglet did to a configlet
+type UpsertResult int
+
+const (
+	UpsertUnchanged UpsertResult = iota
+	UpsertCreated
+	UpsertUpdated
+)
+
+func (r UpsertResult) String() string {
+	switch r {
+	case UpsertCreated:
+		return "Created"
+	case UpsertUpdated:
+		return "Updated"
+	}
+	return "Unchanged"
+}
+
+// configletNotFound is the error code CVP returns for a missing configlet
+const configletNotFound = "132801"
+
+type UpdateConfigletRequest struct {
+	Config         string `json:"config"`
+	Key            string `json:"key"`
+	Name           string `json:"name"`
+	WaitForTaskIds bool   `json:"waitForTaskIds"`
+}
+
+type UpdateConfigletResponse struct {
+	Data         string   `json:"data"`
+	TaskIds      []string `json:"taskIds"`
+	ErrorCode    string   `json:"errorCode"`
+	ErrorMessage string   `json:"errorMessage"`
 }
 
 type DeleteConfiglet struct {
@@ -109,9 +189,26 @@ type ValidateResponse struct {
 		Config string `json:"config"`
 		ID     int    `json:"id"`
 	} `json:"reconciledConfig"`
-	Errors    string `json:"errors"`
-	Reconcile int    `json:"reconcile"`
-	ErrorMsg  string `json:"errorMessage"`
+	Errors         string       `json:"errors"`
+	Reconcile      int          `json:"reconcile"`
+	New            int          `json:"new"`
+	Mismatch       int          `json:"mismatch"`
+	Total          int          `json:"total"`
+	DesignedConfig []ConfigLine `json:"designedConfig"`
+	RunningConfig  []ConfigLine `json:"runningConfig"`
+	ErrorMsg       string       `json:"errorMessage"`
+	// Diff is computed from RunningConfig and DesignedConfig
+	Diff ConfigDiff `json:"-"`
+}
+
+// ConfigLine is one line of a config as laid out by CVP's config compare
+type ConfigLine struct {
+	Command         string `json:"command"`
+	RowID           int    `json:"rowId"`
+	ParentRowID     int    `json:"parentRowId"`
+	BlockID         string `json:"blockId"`
+	Code            string `json:"code"`
+	ShouldReconcile bool   `json:"shouldReconcile"`
 }
 
 type ValidateConfigRequest struct {
@@ -120,8 +217,12 @@ type ValidateConfigRequest struct {
 }
 
 type ValidateConfigResponse struct {
-	WarningCount int `json:"warningCount"`
-	ErrorCount   int `json:"errorCount"`
+	WarningCount int                 `json:"warningCount"`
+	ErrorCount   int                 `json:"errorCount"`
+	Warnings     []ValidationMessage `json:"warnings"`
+	Errors       []ValidationMessage `json:"errors"`
+	ErrorCode    string              `json:"errorCode"`
+	ErrorMessage string              `json:"errorMessage"`
 }
 
 func checkErrors(data JsonData) error {
@@ -133,9 +234,16 @@ func checkErrors(data JsonData) error {
 }
 
 func (c *CvpClient) AddConfiglet(configlet Configlet) (AddConfigletData, error) {
+	return c.addConfiglet(context.Background(), configlet)
+}
+
+func (c *CvpClient) addConfiglet(ctx context.Context, configlet Configlet) (AddConfigletData, error) {
 	addConfigletURL := "/configlet/addConfiglet.do"
-	resp, err := c.Call(configlet, addConfigletURL)
 	body := AddConfigletData{}
+	resp, err := c.CallWithContext(ctx, configlet, addConfigletURL)
+	if err != nil {
+		return body, err
+	}
 	err = json.Unmarshal(resp, &body)
 	if err != nil {
 		log.Printf("Error adding configlet %+v", err)
@@ -147,54 +255,232 @@ func (c *CvpClient) AddConfiglet(configlet Configlet) (AddConfigletData, error)
 	return body, err
 }
 
+// UpdateConfiglet replaces the name, config and note of the configlet
+// identified by key and returns the IDs of any tasks the change generated.
+// An empty note leaves the existing note untouched.
+func (c *CvpClient) UpdateConfiglet(ctx context.Context, key, name, config, note string) ([]string, error) {
+	url := "/configlet/updateConfiglet.do"
+	req := UpdateConfigletRequest{
+		Config:         config,
+		Key:            key,
+		Name:           name,
+		WaitForTaskIds: true,
+	}
+	resp, err := c.CallWithContext(ctx, req, url)
+	if err != nil {
+		return nil, err
+	}
+	body := UpdateConfigletResponse{}
+	if err = json.Unmarshal(resp, &body); err != nil {
+		log.Printf("Error updating configlet %+v", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: body.ErrorCode, ErrorMessage: body.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	if note != "" {
+		if err = c.updateConfigletNote(ctx, key, note); err != nil {
+			return body.TaskIds, err
+		}
+	}
+	return body.TaskIds, nil
+}
+
+func (c *CvpClient) updateConfigletNote(ctx context.Context, key, note string) error {
+	url := "/configlet/addNoteToConfiglet.do"
+	req := struct {
+		Key  string `json:"key"`
+		Note string `json:"note"`
+	}{key, note}
+	resp, err := c.CallWithContext(ctx, req, url)
+	if err != nil {
+		return err
+	}
+	responseBody := JsonData{}
+	if err = json.Unmarshal(resp, &responseBody); err != nil {
+		log.Printf("Error updating configlet note %+v", err)
+		return err
+	}
+	return checkErrors(responseBody)
+}
+
+// RenameConfiglet changes the name of a configlet, keeping its config and note
+func (c *CvpClient) RenameConfiglet(ctx context.Context, oldName, newName string) error {
+	cfglet, found, err := c.findConfiglet(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("No configlet named \"%s\" found", oldName)
+	}
+	_, err = c.UpdateConfiglet(ctx, cfglet.Key, newName, cfglet.Config, cfglet.Note)
+	return err
+}
+
+// ListConfiglets returns the configlets matching filter along with the total
+// number of matches CVP reported, which may exceed the page returned
+func (c *CvpClient) ListConfiglets(ctx context.Context, filter ConfigletFilter) ([]Configlet, int, error) {
+	cfgType := filter.Type
+	if cfgType == "" {
+		cfgType = ConfigletTypeStatic
+	}
+	getConfigletsURL := fmt.Sprintf("/configlet/getConfiglets.do?type=%s&queryparam=%s&startIndex=%d&endIndex=%d",
+		url.QueryEscape(cfgType), url.QueryEscape(filter.Query), filter.Start, filter.End)
+	respbody, err := c.GetWithContext(ctx, getConfigletsURL)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp := ConfigletListData{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListConfiglets :%s\n", err)
+		return nil, 0, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, 0, err
+	}
+	return resp.Data, resp.Total, nil
+}
+
+// UpsertConfiglet creates the configlet if no configlet with its name exists,
+// or updates the existing one when its config or note differ. An empty note
+// leaves the existing note untouched. Task IDs generated by an update are
+// returned alongside what was done.
+func (c *CvpClient) UpsertConfiglet(ctx context.Context, configlet Configlet) (UpsertResult, []string, error) {
+	current, found, err := c.findConfiglet(ctx, configlet.Name)
+	if err != nil {
+		return UpsertUnchanged, nil, err
+	}
+	if !found {
+		// addConfiglet.do rejects any properties other than name and config
+		add := Configlet{Name: configlet.Name, Config: configlet.Config}
+		added, err := c.addConfiglet(ctx, add)
+		if err != nil {
+			return UpsertUnchanged, nil, err
+		}
+		if configlet.Note != "" {
+			if err = c.updateConfigletNote(ctx, added.Data.Key, configlet.Note); err != nil {
+				return UpsertCreated, nil, err
+			}
+		}
+		return UpsertCreated, nil, nil
+	}
+	note := configlet.Note
+	if note == "" {
+		note = current.Note
+	}
+	if SameConfig(current.Config, configlet.Config) && note == current.Note {
+		return UpsertUnchanged, nil, nil
+	}
+	taskIds, err := c.UpdateConfiglet(ctx, current.Key, current.Name, configlet.Config, note)
+	return UpsertUpdated, taskIds, err
+}
+
+// FindConfiglet looks a configlet up by name, unlike GetConfigletByName it
+// escapes the name and tells a missing configlet apart from CVP errors
+func (c *CvpClient) FindConfiglet(ctx context.Context, name string) (Configlet, bool, error) {
+	return c.findConfiglet(ctx, name)
+}
+
+// findConfiglet looks a configlet up by name, unlike GetConfigletByName it
+// tells a missing configlet apart from CVP errors
+func (c *CvpClient) findConfiglet(ctx context.Context, name string) (Configlet, bool, error) {
+	getConfigletURL := "/configlet/getConfigletByName.do?name=" + url.QueryEscape(name)
+	respbody, err := c.GetWithContext(ctx, getConfigletURL)
+	if err != nil {
+		return Configlet{}, false, err
+	}
+	resp := struct {
+		Configlet
+		ErrorCode    string `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding findConfiglet :%s\n", err)
+		return Configlet{}, false, err
+	}
+	if resp.ErrorCode == configletNotFound {
+		return Configlet{}, false, nil
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return Configlet{}, false, err
+	}
+	return resp.Configlet, resp.Key != "", nil
+}
+
+// SameConfig compares two configs ignoring line ending style and trailing
+// newlines, which CVP does not preserve consistently
+func SameConfig(a, b string) bool {
+	normalise := func(s string) string {
+		return strings.TrimRight(strings.Replace(s, "\r\n", "\n", -1), "\n")
+	}
+	return normalise(a) == normalise(b)
+}
+
 // ValidateCompareCfglt takes the netElementId (MAC Address) and a Configlet ID
 // given as Key from adding a configlet, and validates it
 func (c *CvpClient) ValidateCompareCfglt(netElementID string, cfgletIDList []string) (ValidateResponse, error) {
+	return c.validateCompareCfglt(context.Background(), netElementID, cfgletIDList)
+}
+
+func (c *CvpClient) validateCompareCfglt(ctx context.Context, netElementID string, cfgletIDList []string) (ValidateResponse, error) {
 	url := "/provisioning/v2/validateAndCompareConfiglets.do"
 	req := ValidateRequest{
 		NetElementID: netElementID,
 		ConfigIDList: cfgletIDList,
 	}
 	body := ValidateResponse{}
-	resp, err := c.Call(req, url)
+	resp, err := c.CallWithContext(ctx, req, url)
+	if err != nil {
+		return body, err
+	}
 	//log.Printf("Raw response %+v", resp)
 	err = json.Unmarshal(resp, &body)
 	if err != nil {
 		log.Printf("Error validating configlet %+v", err)
+		return body, err
 	}
-	return body, err
+	body.Diff = compareDiff(body.RunningConfig, body.DesignedConfig)
+	return body, nil
 }
 
+// ValidateConfig validates config against a device and returns an error
+// describing the problems if CVP reports any errors, see
+// ValidateConfigDetails for the individual errors and warnings
 func (c *CvpClient) ValidateConfig(netElementID, config string) error {
-	url := "/configlet/validateConfig.do"
-	req := ValidateConfigRequest{
-		NetElementID: netElementID,
-		Config:       config,
-	}
-	resp, err := c.Call(req, url)
-	body := ValidateConfigResponse{}
-	err = json.Unmarshal(resp, &body)
+	result, err := c.ValidateConfigDetails(context.Background(), netElementID, config)
 	if err != nil {
-		log.Printf("Error validating config %+v", err)
-	}
-	if body.ErrorCount > 0 {
-		return fmt.Errorf("Config validation produced errors")
+		return err
 	}
-	return nil
+	return result.Err(false)
 }
 
 func (c *CvpClient) UpdateReconcile(netElementID, cName, cConf string) error {
+	_, err := c.updateReconcile(context.Background(), netElementID, cName, cConf)
+	return err
+}
+
+// updateReconcile creates or updates the reconcile configlet of a device and
+// returns the configlet as stored by CVP
+func (c *CvpClient) updateReconcile(ctx context.Context, netElementID, cName, cConf string) (AddConfigletRepsonse, error) {
 	url := "/provisioning/updateReconcileConfiglet.do?netElementId=" + url.QueryEscape(netElementID)
 	cfg := Configlet{
 		Name:       cName,
 		Config:     cConf,
 		Reconciled: true,
 	}
-	_, err := c.Call(cfg, url)
+	body := AddConfigletData{}
+	resp, err := c.CallWithContext(ctx, cfg, url)
 	if err != nil {
-		return fmt.Errorf("Error updating reconcile configlet")
+		return body.Data, fmt.Errorf("Error updating reconcile configlet")
 	}
-	return nil
+	if err = json.Unmarshal(resp, &body); err != nil {
+		log.Printf("Error decoding reconcile configlet response %+v", err)
+		return body.Data, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: body.ErrorCode, ErrorMessage: body.ErrorMessage}); err != nil {
+		return body.Data, err
+	}
+	return body.Data, nil
 }
 
 /* ApplyConfigletToDevice applies configlets to a device
@@ -204,15 +490,24 @@ func (c *CvpClient) UpdateReconcile(netElementID, cName, cConf string) error {
    cnl -- List of name of configlets to be applied
    (type: List of Strings)
    ckl -- Keys of configlets to be applied (type: List of Strings)
+   save -- Commit the temp action (type: bool)
+
+   With save set, a failure discards every unsaved temp action, see
+   CancelTopology. Without it the error is returned and the provisioning
+   session is left as it is.
 */
 func (c *CvpClient) ApplyConfigletToDevice(deviceIP, deviceName, deviceMac string, cnl []string, save bool) (sdata SaveData, err error) {
-	cfgletCurrent, err := c.GetConfigletByDeviceID(deviceMac)
+	return c.applyConfigletToDevice(context.Background(), deviceIP, deviceName, deviceMac, cnl, save)
+}
+
+func (c *CvpClient) applyConfigletToDevice(ctx context.Context, deviceIP, deviceName, deviceMac string, cnl []string, save bool) (sdata SaveData, err error) {
+	cfgletCurrent, err := c.GetConfigletByDeviceIDWithContext(ctx, deviceMac)
 	if err != nil {
 		log.Printf("Error retrieving configlets from a device")
 		return sdata, err
 	}
 	log.Printf("New configlets : %+v", cnl)
-	cfgletNew, err := c.getConfigletsByName(cnl)
+	cfgletNew, err := c.getConfigletsByName(ctx, cnl)
 	if err != nil {
 		log.Printf("Error retrieving configlets by its name")
 		return sdata, err
@@ -239,22 +534,27 @@ func (c *CvpClient) ApplyConfigletToDevice(deviceIP, deviceName, deviceMac strin
 		IgnoreConfigletBuilderNamesList: []string{},
 	}
 	log.Printf("Applying configlet : %+v", applyCfglet)
-	if err = c.addTempAction(applyCfglet); err != nil {
+	if err = c.addTempAction(ctx, applyCfglet); err != nil {
+		if save {
+			err = c.rollback(ctx, err)
+		}
 		return sdata, err
 	}
 	if save {
-		return c.saveTopologyV2([]string{})
+		if sdata, err = c.saveTopologyV2(ctx, []string{}); err != nil {
+			return sdata, c.rollback(ctx, err)
+		}
 	}
 	return sdata, err
 }
 
-func (c *CvpClient) addTempAction(action Action) error {
+func (c *CvpClient) addTempAction(ctx context.Context, action Action) error {
 	url := "/provisioning/addTempAction.do?format=topology&queryParam=&nodeId=root"
 	dataArray := []Action{action}
 	data := ActionData{
 		Data: dataArray,
 	}
-	resp, err := c.Call(data, url)
+	resp, err := c.CallWithContext(ctx, data, url)
 	if err != nil {
 		log.Printf("Error adding Tempaction :%s\n", err)
 		return err
@@ -272,15 +572,22 @@ func (c *CvpClient) addTempAction(action Action) error {
 
 // GetConfigletByDeviceID gets list of configlets assigned to a device
 func (c *CvpClient) GetConfigletByDeviceID(deviceMac string) ([]Configlet, error) {
-	url := "/provisioning/getConfigletsByNetElementId.do?netElementId=" + deviceMac + "&queryParam=&startIndex=0&endIndex=15"
-	respbody, err := c.Get(url)
-	respConfiglet := ConfigletList{}
-	err = json.Unmarshal(respbody, &respConfiglet)
+	return c.GetConfigletByDeviceIDWithContext(context.Background(), deviceMac)
+}
+
+// GetConfigletByDeviceIDWithContext is GetConfigletByDeviceID with a context
+func (c *CvpClient) GetConfigletByDeviceIDWithContext(ctx context.Context, deviceMac string) ([]Configlet, error) {
+	getConfigletsURL := "/provisioning/getConfigletsByNetElementId.do?netElementId=" + url.QueryEscape(deviceMac) + "&queryParam=&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, getConfigletsURL)
 	if err != nil {
+		return nil, err
+	}
+	respConfiglet := ConfigletList{}
+	if err = json.Unmarshal(respbody, &respConfiglet); err != nil {
 		log.Printf("Error decoding GetConfigletByDeviceID :%s\n", err)
 		return nil, err
 	}
-	return respConfiglet.List, err
+	return respConfiglet.List, nil
 }
 
 func (c *CvpClient) GetConfigletByName(cfglet string) (Configlet, error) {
@@ -295,12 +602,15 @@ func (c *CvpClient) GetConfigletByName(cfglet string) (Configlet, error) {
 	return respConfiglet, err
 }
 
-func (c *CvpClient) getConfigletsByName(cfglets []string) (result []Configlet, err error) {
+func (c *CvpClient) getConfigletsByName(ctx context.Context, cfglets []string) (result []Configlet, err error) {
 	for _, cfgletName := range cfglets {
-		cfglet, err := c.GetConfigletByName(cfgletName)
+		cfglet, found, err := c.findConfiglet(ctx, cfgletName)
 		if err != nil {
 			return result, err
 		}
+		if !found {
+			return result, fmt.Errorf("No configlet named \"%s\" found", cfgletName)
+		}
 		result = append(result, cfglet)
 	}
 	return result, nil
@@ -308,7 +618,7 @@ func (c *CvpClient) getConfigletsByName(cfglets []string) (result []Configlet, e
 
 func contains(list []Configlet, elem Configlet) bool {
 	for _, t := range list {
-		if t == elem {
+		if t.Key == elem.Key {
 			return true
 		}
 	}
@@ -316,12 +626,7 @@ func contains(list []Configlet, elem Configlet) bool {
 }
 
 func (c *CvpClient) filterCfglet(all, remove []Configlet) (stay []Configlet) {
-	for _, cfglet := range all {
-		if !contains(remove, cfglet) {
-			stay = append(stay, cfglet)
-		}
-	}
-	return stay
+	return WithoutConfiglets(all, remove)
 }
 
 func (c *CvpClient) mergeCfglet(current, new []Configlet) (all []Configlet) {
@@ -350,13 +655,16 @@ func getKeys(cfglets []Configlet) []string {
 	return result
 }
 
-// RemoveConfigletFromDevice removes configlets (list of strings) from device
+// RemoveConfigletFromDevice removes configlets (list of strings) from device.
+// With save set, a failure discards every unsaved temp action, see
+// CancelTopology. Without it the session is left as it is.
 func (c *CvpClient) RemoveConfigletFromDevice(deviceIP, deviceName, deviceMac string, cfgletRemoveNames []string, save bool) (sdata SaveData, err error) {
-	cfgletAll, err := c.GetConfigletByDeviceID(deviceMac)
+	ctx := context.Background()
+	cfgletAll, err := c.GetConfigletByDeviceIDWithContext(ctx, deviceMac)
 	if err != nil {
 		return sdata, err
 	}
-	cfgletRemove, err := c.getConfigletsByName(cfgletRemoveNames)
+	cfgletRemove, err := c.getConfigletsByName(ctx, cfgletRemoveNames)
 	if err != nil {
 		return sdata, err
 	}
@@ -381,18 +689,23 @@ func (c *CvpClient) RemoveConfigletFromDevice(deviceIP, deviceName, deviceMac st
 		IgnoreConfigletBuilderNamesList: []string{},
 	}
 	log.Printf("Removing configlet : %+v", removeCfglet)
-	if err = c.addTempAction(removeCfglet); err != nil {
+	if err = c.addTempAction(ctx, removeCfglet); err != nil {
+		if save {
+			err = c.rollback(ctx, err)
+		}
 		return sdata, err
 	}
 	if save {
-		return c.saveTopologyV2([]string{})
+		if sdata, err = c.saveTopologyV2(ctx, []string{}); err != nil {
+			return sdata, c.rollback(ctx, err)
+		}
 	}
 	return sdata, err
 }
 
-func (c *CvpClient) saveTopologyV2(data []string) (SaveData, error) {
+func (c *CvpClient) saveTopologyV2(ctx context.Context, data []string) (SaveData, error) {
 	url := "/provisioning/v2/saveTopology.do"
-	respbody, err := c.Call(data, url)
+	respbody, err := c.CallWithContext(ctx, data, url)
 	resp := SaveData{}
 	err = json.Unmarshal(respbody, &resp)
 	if err != nil {
@@ -417,44 +730,16 @@ func (c *CvpClient) ExecuteTasks(taskIds []string) error {
 	return err
 }
 
+// CheckTasks waits up to seconds for all tasks to complete, see
+// WaitForTasks for more control over the polling
 func (c *CvpClient) CheckTasks(taskIds []string, seconds int) error {
-	getTaskURL := "/task/getTaskById.do?taskId="
-
-	timeout := time.After(time.Duration(seconds) * time.Second)
-
-	for {
-		select {
-		case <-timeout:
-			return fmt.Errorf("Some Tasks are still not completed")
-		default:
-		}
-		response := c.checkCompletion(getTaskURL, taskIds)
-		if response == "ok" {
-			return nil
-		}
-		time.Sleep(1 * time.Second)
-		continue
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second)
+	defer cancel()
+	_, err := c.WaitForTasks(ctx, taskIds, WaitOptions{})
+	if err == context.DeadlineExceeded {
+		return fmt.Errorf("Some Tasks are still not completed%s", c.incompleteTaskLogs(context.Background(), taskIds))
 	}
-}
-
-func (c *CvpClient) checkCompletion(url string, taskIds []string) string {
-	for _, taskID := range taskIds {
-		taskURL := url + taskID
-		resp, err := c.Get(taskURL)
-		if err != nil {
-			return "nok"
-		}
-		responseBody := struct {
-			State string `json:"workOrderState"`
-		}{}
-		if err = json.Unmarshal(resp, &responseBody); err != nil {
-			return "nok"
-		}
-		if responseBody.State != "COMPLETED" {
-			return "nok"
-		}
-	}
-	return "ok"
+	return err
 }
 
 // DeleteConfiglet deletes configlet from CVP
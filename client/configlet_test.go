@@ -1,6 +1,7 @@
 package cvpgo
 
 import (
+	"context"
 	"log"
 	"testing"
 )
@@ -169,6 +170,81 @@ func TestRemoveConfiglet(t *testing.T) {
 	}
 }
 
+func TestUpdateConfiglet(t *testing.T) {
+	data := buildConfigletTestData()
+	t.Logf("Test data: %+v", data)
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	cfglet, err := cvp.GetConfigletByName(data.NewCfglet)
+	if err != nil {
+		t.Fatalf("Error getting configlet : %s", err)
+	}
+	taskIds, err := cvp.UpdateConfiglet(context.Background(), cfglet.Key, cfglet.Name, data.NewConfig+"\n", "updated by test")
+	if err != nil {
+		t.Errorf("Error updating configlet : %s", err)
+	}
+	t.Logf("Update generated tasks %+v", taskIds)
+}
+
+func TestListConfiglets(t *testing.T) {
+	data := buildConfigletTestData()
+	t.Logf("Test data: %+v", data)
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	cfglets, total, err := cvp.ListConfiglets(context.Background(), ConfigletFilter{Query: data.NewCfglet})
+	if err != nil {
+		t.Errorf("Error listing configlets : %s", err)
+	}
+	if total == 0 || len(cfglets) == 0 {
+		t.Errorf("Configlet %s was not listed", data.NewCfglet)
+	}
+}
+
+func TestRenameConfiglet(t *testing.T) {
+	data := buildConfigletTestData()
+	t.Logf("Test data: %+v", data)
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	ctx := context.Background()
+	if err := cvp.RenameConfiglet(ctx, data.NewCfglet, data.NewCfglet+"-renamed"); err != nil {
+		t.Fatalf("Error renaming configlet : %s", err)
+	}
+	if err := cvp.RenameConfiglet(ctx, data.NewCfglet+"-renamed", data.NewCfglet); err != nil {
+		t.Errorf("Error renaming configlet back : %s", err)
+	}
+}
+
+func TestUpsertConfiglet(t *testing.T) {
+	data := buildConfigletTestData()
+	t.Logf("Test data: %+v", data)
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	ctx := context.Background()
+	configlet := Configlet{
+		Name:   data.NewCfglet,
+		Config: data.NewConfig,
+	}
+	if _, _, err := cvp.UpsertConfiglet(ctx, configlet); err != nil {
+		t.Fatalf("Error upserting configlet : %s", err)
+	}
+	result, taskIds, err := cvp.UpsertConfiglet(ctx, configlet)
+	if err != nil {
+		t.Errorf("Error upserting configlet : %s", err)
+	}
+	if result != UpsertUnchanged || len(taskIds) != 0 {
+		t.Errorf("Expected repeated upsert to be a no-op, got %s with tasks %+v", result, taskIds)
+	}
+}
+
+func TestDiffConfiglet(t *testing.T) {
+	data := buildConfigletTestData()
+	t.Logf("Test data: %+v", data)
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	diff, err := cvp.DiffConfiglet(context.Background(), data.NewCfglet, data.NewConfig)
+	if err != nil {
+		t.Errorf("Error diffing configlet : %s", err)
+	}
+	if diff.Exists && diff.Diff.HasChanges() {
+		t.Errorf("Expected no changes, got:\n%s", diff.Unified())
+	}
+}
+
 func TestDeleteConfiglet(t *testing.T) {
 	data := buildConfigletTestData()
 	t.Logf("Test data: %+v", data)
@@ -0,0 +1,234 @@
+package cvpgo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fredhsu/cvpgo/eosconfig"
+)
+
+// StackedConfiglet is a configlet applied to a device, Container is the
+// container it is inherited from and empty when it is assigned to the
+// device itself
+type StackedConfiglet struct {
+	Name      string
+	Key       string
+	Container string
+}
+
+// OverriddenLine is a line of a configlet replaced by a later configlet
+type OverriddenLine struct {
+	Line      string
+	Configlet string
+}
+
+// ConfigletOverlap is a line or stanza of a device config that is defined
+// by more than one configlet
+type ConfigletOverlap struct {
+	// Path holds the enclosing stanzas followed by the line itself
+	Path []string
+	// Configlets lists the configlets defining the line in apply order
+	Configlets []string
+	// Winner is the configlet whose line ends up in the device config
+	Winner string
+	// Overridden holds the lines the winning line replaced, such as
+	// "description old" replaced by "description uplink"
+	Overridden []OverriddenLine
+}
+
+// Conflict reports whether the configlets set different values, rather
+// than repeating the same line or adding to the same stanza
+func (o ConfigletOverlap) Conflict() bool {
+	return len(o.Overridden) > 0
+}
+
+func (o ConfigletOverlap) String() string {
+	s := strings.Join(o.Path, " > ") + ": defined in " + strings.Join(o.Configlets, ", ") + ", " + o.Winner + " wins"
+	for _, line := range o.Overridden {
+		s += fmt.Sprintf(", overrides \"%s\" from %s", line.Line, line.Configlet)
+	}
+	return s
+}
+
+// ConfigletStack is the result of AnalyzeConfigletStack
+type ConfigletStack struct {
+	DeviceID string
+	// Configlets are in apply order, the configlets of the root container
+	// first and the configlets of the device last
+	Configlets []StackedConfiglet
+	Overlaps   []ConfigletOverlap
+	// Designed is the config the stacked configlets add up to
+	Designed *eosconfig.Config
+}
+
+// Conflicts returns the overlaps where a configlet overrides the value
+// set by an earlier one
+func (s ConfigletStack) Conflicts() []ConfigletOverlap {
+	var result []ConfigletOverlap
+	for _, o := range s.Overlaps {
+		if o.Conflict() {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// AnalyzeConfigletStack fetches the configlets applied to the device with
+// the given system MAC address, those inherited from its containers and
+// those assigned to it, and merges them in the order CVP applies them.
+// Lines and stanzas defined by more than one configlet are reported
+// together with the configlet that wins. Builders are skipped, the
+// configlets they generate are analysed instead.
+func (c *CvpClient) AnalyzeConfigletStack(ctx context.Context, deviceID string) (ConfigletStack, error) {
+	stack := ConfigletStack{DeviceID: deviceID}
+	devices, err := c.ListDevices(ctx)
+	if err != nil {
+		return stack, err
+	}
+	var dev *NetElement
+	for i := range devices {
+		if devices[i].SystemMacAddress == deviceID {
+			dev = &devices[i]
+			break
+		}
+	}
+	if dev == nil {
+		return stack, fmt.Errorf("No device with ID \"%s\" found", deviceID)
+	}
+	containers, err := c.containerPath(ctx, dev.ParentContainerKey)
+	if err != nil {
+		return stack, err
+	}
+
+	var applied []Configlet
+	add := func(cfglets []Configlet, container string) {
+		for _, cfglet := range cfglets {
+			if cfglet.Type == ConfigletTypeBuilder || contains(applied, cfglet) {
+				continue
+			}
+			applied = append(applied, cfglet)
+			stack.Configlets = append(stack.Configlets, StackedConfiglet{Name: cfglet.Name, Key: cfglet.Key, Container: container})
+		}
+	}
+	for _, container := range containers {
+		cfglets, err := c.GetConfigletByContainerID(ctx, container.Key)
+		if err != nil {
+			return stack, err
+		}
+		add(cfglets, container.Name)
+	}
+	cfglets, err := c.GetConfigletByDeviceIDWithContext(ctx, deviceID)
+	if err != nil {
+		return stack, err
+	}
+	add(cfglets, "")
+
+	configs := make([]*eosconfig.Config, 0, len(applied))
+	for _, cfglet := range applied {
+		configs = append(configs, eosconfig.Parse(cfglet.Name, cfglet.Config))
+	}
+	stack.Designed = eosconfig.Merge(dev.Fqdn, configs...)
+	stack.Overlaps = configletOverlaps(stack.Designed)
+	return stack, nil
+}
+
+// containerPath returns the containers from the root container down to the
+// container with the given key
+func (c *CvpClient) containerPath(ctx context.Context, key string) ([]Container, error) {
+	all, err := c.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Container, len(all))
+	var current Container
+	for _, container := range all {
+		byName[container.Name] = container
+		if container.Key == key {
+			current = container
+		}
+	}
+	if current.Key == "" {
+		return nil, fmt.Errorf("No container with key \"%s\" found", key)
+	}
+	path := []Container{current}
+	for current.ParentName != "" {
+		parent, ok := byName[current.ParentName]
+		if !ok || len(path) > len(all) {
+			return nil, fmt.Errorf("No container named \"%s\" found", current.ParentName)
+		}
+		path = append([]Container{parent}, path...)
+		current = parent
+	}
+	return path, nil
+}
+
+// InheritedConfiglets returns the configlets a device in the container
+// with the given key inherits, those assigned to the container and to the
+// containers above it. An empty key returns no configlets.
+func (c *CvpClient) InheritedConfiglets(ctx context.Context, containerKey string) ([]Configlet, error) {
+	if containerKey == "" {
+		return nil, nil
+	}
+	containers, err := c.containerPath(ctx, containerKey)
+	if err != nil {
+		return nil, err
+	}
+	var inherited []Configlet
+	for _, container := range containers {
+		cfglets, err := c.GetConfigletByContainerID(ctx, container.Key)
+		if err != nil {
+			return nil, err
+		}
+		inherited = append(inherited, cfglets...)
+	}
+	return inherited, nil
+}
+
+// WithoutConfiglets returns the configlets of list that are not in remove,
+// such as the configlets CVP's device lookup returns without those the
+// device inherits
+func WithoutConfiglets(list, remove []Configlet) []Configlet {
+	var result []Configlet
+	for _, cfglet := range list {
+		if !contains(remove, cfglet) {
+			result = append(result, cfglet)
+		}
+	}
+	return result
+}
+
+// configletOverlaps lists the lines of a merged config set by more than
+// one configlet
+func configletOverlaps(merged *eosconfig.Config) []ConfigletOverlap {
+	var result []ConfigletOverlap
+	merged.Walk(func(path []string, n *eosconfig.Node) {
+		if len(n.Sources) < 2 && len(n.Overridden) == 0 {
+			return
+		}
+		overlap := ConfigletOverlap{Path: path, Winner: n.Source}
+		for _, previous := range n.Overridden {
+			overlap.Configlets = appendUnique(overlap.Configlets, previous.Sources...)
+			overlap.Overridden = append(overlap.Overridden, OverriddenLine{Line: previous.Line, Configlet: previous.Source})
+		}
+		overlap.Configlets = appendUnique(overlap.Configlets, n.Sources...)
+		result = append(result, overlap)
+	})
+	return result
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if existing == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
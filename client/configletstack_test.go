@@ -0,0 +1,69 @@
+package cvpgo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnalyzeConfigletStack(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/inventory/add/searchContainers.do":
+			fmt.Fprint(w, `{"total":2,"data":[{"name":"Leafs","key":"c1","parentName":"Tenant"},{"name":"Tenant","key":"root"}]}`)
+		case "/provisioning/getConfigletsByContainerId.do":
+			switch r.URL.Query().Get("containerId") {
+			case "root":
+				fmt.Fprint(w, `{"configletList":[{"name":"base","key":"k1","type":"Static","config":"hostname leaf\nntp server 10.0.0.1\nvrf instance A\ninterface Ethernet1\n   description old\n"}]}`)
+			case "c1":
+				fmt.Fprint(w, `{"configletList":[{"name":"leafs","key":"k2","type":"Static","config":"ntp server 10.0.0.1\ninterface Ethernet1\n   mtu 9214\n"},{"name":"gen","key":"k3","type":"Builder","config":"print('hostname x')"}]}`)
+			}
+		case "/inventory/getInventory.do":
+			fmt.Fprint(w, `{"total":1,"netElementList":[{"fqdn":"leaf1","systemMacAddress":"00:1c:73:00:00:01","parentContainerKey":"c1"}]}`)
+		case "/provisioning/getConfigletsByNetElementId.do":
+			fmt.Fprint(w, `{"configletList":[{"name":"base","key":"k1","type":"Static"},{"name":"leaf1","key":"k4","type":"Static","config":"hostname leaf1\nvrf instance B\ninterface Ethernet1\n   description uplink\n"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL, Client: srv.Client()}
+
+	stack, err := cvp.AnalyzeConfigletStack(context.Background(), "00:1c:73:00:00:01")
+	if err != nil {
+		t.Fatalf("Error analysing configlet stack : %s", err)
+	}
+	var names []string
+	for _, cfglet := range stack.Configlets {
+		names = append(names, cfglet.Name+"@"+cfglet.Container)
+	}
+	if fmt.Sprint(names) != "[base@Tenant leafs@Leafs leaf1@]" {
+		t.Errorf("Unexpected configlet order %v", names)
+	}
+	expected := []string{
+		`hostname leaf1: defined in base, leaf1, leaf1 wins, overrides "hostname leaf" from base`,
+		`ntp server 10.0.0.1: defined in base, leafs, leafs wins`,
+		`interface Ethernet1: defined in base, leafs, leaf1, leaf1 wins`,
+		`interface Ethernet1 > description uplink: defined in base, leaf1, leaf1 wins, overrides "description old" from base`,
+	}
+	if len(stack.Overlaps) != len(expected) {
+		t.Fatalf("Unexpected overlaps %v", stack.Overlaps)
+	}
+	for i, o := range stack.Overlaps {
+		if o.String() != expected[i] {
+			t.Errorf("Unexpected overlap %q, want %q", o.String(), expected[i])
+		}
+	}
+	if conflicts := stack.Conflicts(); len(conflicts) != 2 {
+		t.Errorf("Unexpected conflicts %v", conflicts)
+	}
+	// different VRFs do not override each other
+	if stack.Designed.Lookup("vrf instance A") == nil || stack.Designed.Lookup("vrf instance B") == nil {
+		t.Errorf("Expected both VRFs in the designed config\n%s", stack.Designed)
+	}
+	if stack.Designed.Lookup("interface Ethernet1", "mtu 9214") == nil {
+		t.Errorf("Unexpected designed config\n%s", stack.Designed)
+	}
+}
@@ -0,0 +1,266 @@
+package cvpgo
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// DiffOp is the kind of change a DiffLine represents
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffAdded
+	DiffRemoved
+	DiffChanged
+)
+
+func (op DiffOp) String() string {
+	switch op {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+	return "equal"
+}
+
+// DiffLine is one line of a ConfigDiff. Old is the line in the current
+// (running) config and New the line in the proposed (designed) config,
+// OldLine and NewLine are their 1-based line numbers or 0 when absent.
+type DiffLine struct {
+	Op      DiffOp
+	Old     string
+	New     string
+	OldLine int
+	NewLine int
+	// Code is CVP's compliance code for the line, if the diff came from CVP
+	Code string
+}
+
+// ConfigDiff is a line level difference between two configs
+type ConfigDiff struct {
+	OldName string
+	NewName string
+	Lines   []DiffLine
+	Added   int
+	Removed int
+	Changed int
+}
+
+func newConfigDiff(oldName, newName string, lines []DiffLine) ConfigDiff {
+	diff := ConfigDiff{OldName: oldName, NewName: newName, Lines: lines}
+	for _, line := range lines {
+		switch line.Op {
+		case DiffAdded:
+			diff.Added++
+		case DiffRemoved:
+			diff.Removed++
+		case DiffChanged:
+			diff.Changed++
+		}
+	}
+	return diff
+}
+
+// HasChanges reports whether the two configs differ
+func (d ConfigDiff) HasChanges() bool {
+	return d.Added+d.Removed+d.Changed > 0
+}
+
+// compareDiff builds a ConfigDiff from CVP's side by side compare, where
+// running and designed lines with the same index belong together and an
+// empty command marks a line missing on that side
+func compareDiff(running, designed []ConfigLine) ConfigDiff {
+	if len(running) != len(designed) {
+		return diffLines("running", "designed", commands(running), commands(designed))
+	}
+	var lines []DiffLine
+	oldLine, newLine := 0, 0
+	for i := range running {
+		r, d := running[i], designed[i]
+		line := DiffLine{Old: r.Command, New: d.Command, Code: d.Code}
+		if line.Code == "" {
+			line.Code = r.Code
+		}
+		if r.Command != "" {
+			oldLine++
+			line.OldLine = oldLine
+		}
+		if d.Command != "" {
+			newLine++
+			line.NewLine = newLine
+		}
+		switch {
+		case r.Command == "" && d.Command == "":
+			continue
+		case r.Command == "":
+			line.Op = DiffAdded
+		case d.Command == "":
+			line.Op = DiffRemoved
+		case r.Command != d.Command:
+			line.Op = DiffChanged
+		}
+		lines = append(lines, line)
+	}
+	return newConfigDiff("running", "designed", lines)
+}
+
+func commands(lines []ConfigLine) []string {
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line.Command != "" {
+			result = append(result, line.Command)
+		}
+	}
+	return result
+}
+
+// diffLines computes the shortest edit script between old and new using
+// Myers' algorithm
+func diffLines(oldName, newName string, old, new []string) ConfigDiff {
+	n, m := len(old), len(new)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+search:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && old[x] == new[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// walk the trace backwards to recover the edits, last edit first
+	var reversed []DiffLine
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffLine{Op: DiffEqual, Old: old[x-1], New: new[y-1], OldLine: x, NewLine: y})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, DiffLine{Op: DiffAdded, New: new[y-1], NewLine: y})
+			} else {
+				reversed = append(reversed, DiffLine{Op: DiffRemoved, Old: old[x-1], OldLine: x})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	lines := make([]DiffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return newConfigDiff(oldName, newName, lines)
+}
+
+// unifiedLine is a single line of unified diff output
+type unifiedLine struct {
+	prefix byte
+	text   string
+}
+
+// Unified renders the diff in unified diff format with the given number of
+// context lines around each change
+func (d ConfigDiff) Unified(context int) string {
+	if !d.HasChanges() {
+		return ""
+	}
+	var out []unifiedLine
+	for _, line := range d.Lines {
+		switch line.Op {
+		case DiffEqual:
+			out = append(out, unifiedLine{' ', line.Old})
+		case DiffAdded:
+			out = append(out, unifiedLine{'+', line.New})
+		case DiffRemoved:
+			out = append(out, unifiedLine{'-', line.Old})
+		case DiffChanged:
+			out = append(out, unifiedLine{'-', line.Old}, unifiedLine{'+', line.New})
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", d.OldName, d.NewName)
+	for i := 0; i < len(out); {
+		if out[i].prefix == ' ' {
+			i++
+			continue
+		}
+		// out[i] is the first change of a hunk, changes separated by no
+		// more than twice the context belong to the same hunk
+		last := i
+		for j := i; j < len(out) && j-last <= 2*context+1; j++ {
+			if out[j].prefix != ' ' {
+				last = j
+			}
+		}
+		start, end := i-context, last+context+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(out) {
+			end = len(out)
+		}
+		oldStart, newStart := countLines(out[:start])
+		oldCount, newCount := countLines(out[start:end])
+		if oldCount > 0 {
+			oldStart++
+		}
+		if newCount > 0 {
+			newStart++
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, l := range out[start:end] {
+			buf.WriteByte(l.prefix)
+			buf.WriteString(l.text)
+			buf.WriteByte('\n')
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+// countLines returns how many old and new lines a unified diff slice covers
+func countLines(lines []unifiedLine) (old, new int) {
+	for _, l := range lines {
+		if l.prefix != '+' {
+			old++
+		}
+		if l.prefix != '-' {
+			new++
+		}
+	}
+	return old, new
+}
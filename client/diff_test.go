@@ -0,0 +1,64 @@
+package cvpgo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	old := []string{"hostname A", "interface Ethernet1", "   shutdown", "!"}
+	new := []string{"hostname A", "interface Ethernet1", "   description uplink", "!", "end"}
+	diff := diffLines("old", "new", old, new)
+	if diff.Added != 2 || diff.Removed != 1 {
+		t.Errorf("Expected 2 added and 1 removed lines, got %+v", diff)
+	}
+	expected := `--- old
++++ new
+@@ -1,4 +1,5 @@
+ hostname A
+ interface Ethernet1
+-   shutdown
++   description uplink
+ !
++end
+`
+	if got := diff.Unified(3); got != expected {
+		t.Errorf("Unexpected unified diff:\n%s", got)
+	}
+}
+
+func TestDiffLinesHunks(t *testing.T) {
+	var old []string
+	for i := 0; i < 20; i++ {
+		old = append(old, strings.Repeat("x", i+1))
+	}
+	new := append([]string{"first"}, old[1:]...)
+	new[18] = "last"
+	unified := diffLines("old", "new", old, new).Unified(1)
+	if strings.Count(unified, "@@ -") != 2 {
+		t.Errorf("Expected two hunks:\n%s", unified)
+	}
+	if !strings.Contains(unified, "@@ -18,3 +18,3 @@") {
+		t.Errorf("Unexpected second hunk header:\n%s", unified)
+	}
+}
+
+func TestCompareDiff(t *testing.T) {
+	running := []ConfigLine{{Command: "hostname A"}, {Command: "ntp server 1.1.1.1"}, {}}
+	designed := []ConfigLine{{Command: "hostname B"}, {}, {Command: "ntp server 2.2.2.2"}}
+	diff := compareDiff(running, designed)
+	if diff.Changed != 1 || diff.Removed != 1 || diff.Added != 1 {
+		t.Errorf("Unexpected diff counts %+v", diff)
+	}
+	if diff.Lines[2].NewLine != 2 || diff.Lines[2].OldLine != 0 {
+		t.Errorf("Unexpected line numbers %+v", diff.Lines[2])
+	}
+}
+
+func TestDiffLinesEqual(t *testing.T) {
+	lines := []string{"a", "b"}
+	diff := diffLines("old", "new", lines, lines)
+	if diff.HasChanges() || diff.Unified(3) != "" {
+		t.Errorf("Expected no changes, got %+v", diff)
+	}
+}
@@ -0,0 +1,130 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Event severities reported by CVP in Event.Severity
+const (
+	EventInfo     = "INFO"
+	EventWarning  = "WARNING"
+	EventError    = "ERROR"
+	EventCritical = "CRITICAL"
+)
+
+// Event is a CVP event or alert raised for a device
+type Event struct {
+	Key            string `json:"key"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	EventType      string `json:"eventType"`
+	Severity       string `json:"severity"`
+	ObjectID       string `json:"objectId"`
+	ObjectName     string `json:"objectName"`
+	Acknowledged   bool   `json:"isAcknowledged"`
+	AcknowledgedBy string `json:"acknowledgedBy"`
+	Timestamp      int64  `json:"timestamp"`
+}
+
+// Time returns the time the event was raised
+func (e Event) Time() time.Time {
+	return time.Unix(0, e.Timestamp*int64(time.Millisecond))
+}
+
+// EventFilter selects the events returned by ListEvents, zero fields match
+// any event. Device matches the ID or name of the device the event was
+// raised for and Acknowledged, when set, the acknowledged state. Start and
+// End select a page of the matching events, an End of 0 returns all of them.
+type EventFilter struct {
+	Severity     string
+	Device       string
+	Acknowledged *bool
+	Since        time.Time
+	Until        time.Time
+	Start        int
+	End          int
+}
+
+func (f EventFilter) matches(e Event) bool {
+	if f.Severity != "" && !strings.EqualFold(f.Severity, e.Severity) {
+		return false
+	}
+	if f.Device != "" && f.Device != e.ObjectID && f.Device != e.ObjectName {
+		return false
+	}
+	if f.Acknowledged != nil && *f.Acknowledged != e.Acknowledged {
+		return false
+	}
+	return inTimeRange(e.Time(), f.Since, f.Until)
+}
+
+// pageOnly reports whether the filter only selects a page, which CVP can
+// apply itself
+func (f EventFilter) pageOnly() bool {
+	return f.Severity == "" && f.Device == "" && f.Acknowledged == nil && f.Since.IsZero() && f.Until.IsZero()
+}
+
+// ListEvents returns the page of events matching filter along with the
+// total number of matching events. Events keep CVP's order, most recent
+// first, so that a page is the same whether CVP or the client picks it.
+// CVP cannot filter events, a filter on anything but the page fetches all
+// events and filters and pages them here.
+func (c *CvpClient) ListEvents(ctx context.Context, filter EventFilter) ([]Event, int, error) {
+	serverSide := filter.pageOnly()
+	start, end := 0, 0
+	if serverSide {
+		start, end = filter.Start, filter.End
+	}
+	eventsURL := fmt.Sprintf("/event/getAllEvents.do?startIndex=%d&endIndex=%d&isCompletedRequired=true", start, end)
+	respbody, err := c.GetWithContext(ctx, eventsURL)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp := struct {
+		Total        int     `json:"total"`
+		Data         []Event `json:"data"`
+		ErrorCode    string  `json:"errorCode"`
+		ErrorMessage string  `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListEvents :%s\n", err)
+		return nil, 0, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, 0, err
+	}
+	if serverSide {
+		return resp.Data, resp.Total, nil
+	}
+	var events []Event
+	for _, e := range resp.Data {
+		if filter.matches(e) {
+			events = append(events, e)
+		}
+	}
+	start, end = page(filter.Start, filter.End, len(events))
+	return events[start:end], len(events), nil
+}
+
+// AcknowledgeEvents marks events as acknowledged
+func (c *CvpClient) AcknowledgeEvents(ctx context.Context, keys []string) error {
+	url := "/event/eventAcknowledge.do"
+	data := struct {
+		Data []string `json:"data"`
+	}{keys}
+	resp, err := c.CallWithContext(ctx, data, url)
+	if err != nil {
+		return err
+	}
+	responseBody := JsonData{}
+	if err = json.Unmarshal(resp, &responseBody); err != nil {
+		log.Printf("Error acknowledging events %+v", err)
+		return err
+	}
+	return checkErrors(responseBody)
+}
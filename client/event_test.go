@@ -0,0 +1,21 @@
+package cvpgo
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListEvents(t *testing.T) {
+	data := buildConfigletTestData()
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	acknowledged := false
+	events, _, err := cvp.ListEvents(context.Background(), EventFilter{Acknowledged: &acknowledged})
+	if err != nil {
+		t.Fatalf("Error listing events : %s", err)
+	}
+	for _, e := range events {
+		if e.Acknowledged {
+			t.Errorf("Unexpected acknowledged event %+v", e)
+		}
+	}
+}
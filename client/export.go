@@ -0,0 +1,234 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Files and directories of an export, relative to the export directory
+const (
+	ExportConfigletDir  = "configlets"
+	ExportContainerFile = "containers.json"
+	ExportDeviceFile    = "devices.json"
+)
+
+// ExportedConfiglet is the metadata of an exported configlet, its config is
+// stored next to it in a .cfg file
+type ExportedConfiglet struct {
+	Name                 string `json:"name"`
+	Key                  string `json:"key"`
+	Type                 string `json:"type,omitempty"`
+	Note                 string `json:"note,omitempty"`
+	User                 string `json:"user,omitempty"`
+	Reconciled           bool   `json:"reconciled,omitempty"`
+	DateTimeInLongFormat int64  `json:"dateTimeInLongFormat,omitempty"`
+}
+
+// ExportedContainer is a container of the exported container tree with the
+// names of the static configlets assigned to it
+type ExportedContainer struct {
+	Name       string   `json:"name"`
+	Key        string   `json:"key"`
+	Parent     string   `json:"parent,omitempty"`
+	Configlets []string `json:"configlets"`
+}
+
+// ExportedDevice is the placement of a device and the names of the static
+// configlets assigned to it
+type ExportedDevice struct {
+	Hostname         string   `json:"hostname"`
+	SerialNumber     string   `json:"serialNumber"`
+	SystemMacAddress string   `json:"systemMacAddress"`
+	IPAddress        string   `json:"ipAddress"`
+	Container        string   `json:"container"`
+	Configlets       []string `json:"configlets"`
+}
+
+// Export writes CVP's provisioning state below dir: every static configlet
+// as configlets/<name>.cfg with its metadata in configlets/<name>.json, the
+// container tree in containers.json and the devices with their container
+// and configlets in devices.json. Everything is sorted by name so that
+// unchanged state exports to identical files, and configlet files of
+// configlets that no longer exist are removed.
+func (c *CvpClient) Export(ctx context.Context, dir string) error {
+	cfgletDir := filepath.Join(dir, ExportConfigletDir)
+	if err := os.MkdirAll(cfgletDir, 0755); err != nil {
+		return err
+	}
+	configlets, _, err := c.ListConfiglets(ctx, ConfigletFilter{})
+	if err != nil {
+		return err
+	}
+	written := make(map[string]bool)
+	for _, listed := range configlets {
+		cfglet, found, err := c.findConfiglet(ctx, listed.Name)
+		if err != nil {
+			return err
+		}
+		if !found {
+			// deleted since it was listed
+			continue
+		}
+		base := ExportFileName(cfglet.Name)
+		if written[base+".cfg"] {
+			return fmt.Errorf("Configlets \"%s\" and another configlet export to the same file %s.cfg", cfglet.Name, base)
+		}
+		meta := ExportedConfiglet{
+			Name:                 cfglet.Name,
+			Key:                  cfglet.Key,
+			Type:                 cfglet.Type,
+			Note:                 cfglet.Note,
+			User:                 cfglet.User,
+			Reconciled:           cfglet.Reconciled,
+			DateTimeInLongFormat: cfglet.DateTimeInLongFormat,
+		}
+		if err = writeExportFile(filepath.Join(cfgletDir, base+".cfg"), []byte(cfglet.Config)); err != nil {
+			return err
+		}
+		if err = writeExportJSON(filepath.Join(cfgletDir, base+".json"), meta); err != nil {
+			return err
+		}
+		written[base+".cfg"], written[base+".json"] = true, true
+	}
+	if err = removeStale(cfgletDir, written); err != nil {
+		return err
+	}
+
+	containers, err := c.exportContainers(ctx)
+	if err != nil {
+		return err
+	}
+	if err = writeExportJSON(filepath.Join(dir, ExportContainerFile), containers); err != nil {
+		return err
+	}
+	devices, err := c.exportDevices(ctx, containers)
+	if err != nil {
+		return err
+	}
+	return writeExportJSON(filepath.Join(dir, ExportDeviceFile), devices)
+}
+
+func (c *CvpClient) exportContainers(ctx context.Context) ([]ExportedContainer, error) {
+	containers, err := c.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exported := make([]ExportedContainer, 0, len(containers))
+	for _, container := range containers {
+		assigned, err := c.GetConfigletByContainerID(ctx, container.Key)
+		if err != nil {
+			return nil, err
+		}
+		exported = append(exported, ExportedContainer{
+			Name:       container.Name,
+			Key:        container.Key,
+			Parent:     container.ParentName,
+			Configlets: staticConfigletNames(assigned),
+		})
+	}
+	sort.Slice(exported, func(i, j int) bool { return exported[i].Name < exported[j].Name })
+	return exported, nil
+}
+
+func (c *CvpClient) exportDevices(ctx context.Context, containers []ExportedContainer) ([]ExportedDevice, error) {
+	containerNames := make(map[string]string, len(containers))
+	for _, container := range containers {
+		containerNames[container.Key] = container.Name
+	}
+	devices, err := c.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exported := make([]ExportedDevice, 0, len(devices))
+	inherited := make(map[string][]Configlet)
+	for _, dev := range devices {
+		assigned, err := c.GetConfigletByDeviceIDWithContext(ctx, dev.SystemMacAddress)
+		if err != nil {
+			return nil, err
+		}
+		// the configlets inherited from containers are exported with them
+		if _, ok := inherited[dev.ParentContainerKey]; !ok {
+			if inherited[dev.ParentContainerKey], err = c.InheritedConfiglets(ctx, dev.ParentContainerKey); err != nil {
+				return nil, err
+			}
+		}
+		assigned = WithoutConfiglets(assigned, inherited[dev.ParentContainerKey])
+		exported = append(exported, ExportedDevice{
+			Hostname:         dev.Fqdn,
+			SerialNumber:     dev.SerialNumber,
+			SystemMacAddress: dev.SystemMacAddress,
+			IPAddress:        dev.IPAddress,
+			Container:        containerNames[dev.ParentContainerKey],
+			Configlets:       staticConfigletNames(assigned),
+		})
+	}
+	sort.Slice(exported, func(i, j int) bool { return exported[i].Hostname < exported[j].Hostname })
+	return exported, nil
+}
+
+// staticConfigletNames returns the names of the static configlets in the
+// order they are assigned, builders and generated configlets are
+// recreated by their builders
+func staticConfigletNames(configlets []Configlet) []string {
+	names := []string{}
+	for _, cfglet := range configlets {
+		if cfglet.IsStatic() {
+			names = append(names, cfglet.Name)
+		}
+	}
+	return names
+}
+
+// ExportFileName returns the file name, without extension, a configlet is
+// exported to. Characters that are not safe in file names are replaced.
+func ExportFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', 0:
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func writeExportJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeExportFile(name, append(data, '\n'))
+}
+
+// writeExportFile writes data to name unless the file already holds it, so
+// that an unchanged export leaves file times untouched
+func writeExportFile(name string, data []byte) error {
+	if current, err := ioutil.ReadFile(name); err == nil && string(current) == string(data) {
+		return nil
+	}
+	return ioutil.WriteFile(name, data, 0644)
+}
+
+// removeStale removes the exported configlet files in dir that are not in
+// written, other files are left alone
+func removeStale(dir string, written map[string]bool) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || written[f.Name()] || (ext != ".cfg" && ext != ".json") {
+			continue
+		}
+		if err = os.Remove(filepath.Join(dir, f.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
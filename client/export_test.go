@@ -0,0 +1,102 @@
+package cvpgo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newExportTestServer serves a CVP with two configlets, a container below
+// the root container and one device in it
+func newExportTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/configlet/getConfiglets.do":
+			fmt.Fprint(w, `{"total":2,"data":[{"name":"base"},{"name":"leaf/intf"}]}`)
+		case "/configlet/getConfigletByName.do":
+			fmt.Fprintf(w, `{"name":"%s","key":"key-%s","config":"hostname %s\n","type":"Static","note":"n"}`,
+				q.Get("name"), q.Get("name"), q.Get("name"))
+		case "/inventory/add/searchContainers.do":
+			fmt.Fprint(w, `{"total":2,"data":[{"name":"Tenant","key":"root"},{"name":"Leafs","key":"c1","parentName":"Tenant"}]}`)
+		case "/provisioning/getConfigletsByContainerId.do":
+			if q.Get("containerId") == "c1" {
+				fmt.Fprint(w, `{"configletList":[{"name":"base","key":"key-base","type":"Static"},{"name":"gen","key":"key-gen","type":"Builder"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"configletList":[]}`)
+		case "/inventory/getInventory.do":
+			fmt.Fprint(w, `{"total":1,"netElementList":[{"fqdn":"leaf1","serialNumber":"SN1","systemMacAddress":"00:1c:73:00:00:01","ipAddress":"10.0.0.1","parentContainerKey":"c1"}]}`)
+		case "/provisioning/getConfigletsByNetElementId.do":
+			// CVP also returns the configlets inherited from Leafs
+			fmt.Fprint(w, `{"configletList":[{"name":"base","key":"key-base","type":"Static"},{"name":"leaf/intf","key":"key-leaf/intf","type":"Static"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestExport(t *testing.T) {
+	srv := newExportTestServer()
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL, Client: srv.Client()}
+	dir, err := ioutil.TempDir("", "cvpgo-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stale := filepath.Join(dir, ExportConfigletDir, "deleted.cfg")
+	os.MkdirAll(filepath.Dir(stale), 0755)
+	ioutil.WriteFile(stale, []byte("hostname old\n"), 0644)
+
+	if err = cvp.Export(context.Background(), dir); err != nil {
+		t.Fatalf("Error exporting : %s", err)
+	}
+	cfg, err := ioutil.ReadFile(filepath.Join(dir, ExportConfigletDir, "leaf_intf.cfg"))
+	if err != nil || string(cfg) != "hostname leaf/intf\n" {
+		t.Errorf("Unexpected configlet file %q (%v)", cfg, err)
+	}
+	if _, err = os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("Stale configlet file was not removed")
+	}
+	containers, _ := ioutil.ReadFile(filepath.Join(dir, ExportContainerFile))
+	wantContainers := `[
+  {
+    "name": "Leafs",
+    "key": "c1",
+    "parent": "Tenant",
+    "configlets": [
+      "base"
+    ]
+  },
+  {
+    "name": "Tenant",
+    "key": "root",
+    "configlets": []
+  }
+]
+`
+	if string(containers) != wantContainers {
+		t.Errorf("Unexpected containers file\n%s", containers)
+	}
+	devices, _ := ioutil.ReadFile(filepath.Join(dir, ExportDeviceFile))
+	if !strings.Contains(string(devices), `"configlets": [
+      "leaf/intf"
+    ]`) {
+		t.Errorf("Unexpected devices file\n%s", devices)
+	}
+
+	if err = cvp.Export(context.Background(), dir); err != nil {
+		t.Fatalf("Error exporting again : %s", err)
+	}
+	again, _ := ioutil.ReadFile(filepath.Join(dir, ExportDeviceFile))
+	if string(again) != string(devices) {
+		t.Errorf("Export is not deterministic\n%s\n%s", devices, again)
+	}
+}
@@ -0,0 +1,254 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+)
+
+// Image is an EOS image or extension uploaded to CVP
+type Image struct {
+	Key              string `json:"key"`
+	ImageID          string `json:"imageId"`
+	Name             string `json:"name"`
+	ImageSize        string `json:"imageSize"`
+	MD5              string `json:"md5"`
+	IsRebootRequired string `json:"isRebootRequired"`
+}
+
+// ImageBundle is a named set of images applied to devices together
+type ImageBundle struct {
+	Key                    string  `json:"key"`
+	Name                   string  `json:"name"`
+	IsCertifiedImage       string  `json:"isCertifiedImage"`
+	Images                 []Image `json:"images"`
+	AppliedContainersCount int     `json:"appliedContainersCount"`
+	AppliedDevicesCount    int     `json:"appliedDevicesCount"`
+}
+
+// ImageBundleTarget is the device or container an image bundle is applied
+// to, see DeviceTarget and ContainerTarget
+type ImageBundleTarget struct {
+	Type string
+	ID   string
+	Name string
+}
+
+// DeviceTarget returns the target for applying an image bundle to a device
+func DeviceTarget(dev NetElement) ImageBundleTarget {
+	return ImageBundleTarget{Type: "netelement", ID: dev.SystemMacAddress, Name: dev.Fqdn}
+}
+
+// ContainerTarget returns the target for applying an image bundle to all
+// devices in a container
+func ContainerTarget(container Container) ImageBundleTarget {
+	return ImageBundleTarget{Type: "container", ID: container.Key, Name: container.Name}
+}
+
+// UploadImage uploads an EOS SWI or extension read from r under the given
+// file name
+func (c *CvpClient) UploadImage(ctx context.Context, name string, r io.Reader) (Image, error) {
+	resp, err := c.Upload(ctx, "/image/addImage.do", name, r)
+	if err != nil {
+		return Image{}, err
+	}
+	body := struct {
+		Image
+		Result       string `json:"result"`
+		ErrorCode    string `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(resp, &body); err != nil {
+		log.Printf("Error uploading image %+v", err)
+		return Image{}, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: body.ErrorCode, ErrorMessage: body.ErrorMessage}); err != nil {
+		return Image{}, err
+	}
+	return body.Image, nil
+}
+
+// ListImages returns all images uploaded to CVP
+func (c *CvpClient) ListImages(ctx context.Context) ([]Image, error) {
+	imagesURL := "/image/getImages.do?queryparam=&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, imagesURL)
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Total        int     `json:"total"`
+		Data         []Image `json:"data"`
+		ErrorCode    string  `json:"errorCode"`
+		ErrorMessage string  `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListImages :%s\n", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// ListImageBundles returns all image bundles, without their images
+func (c *CvpClient) ListImageBundles(ctx context.Context) ([]ImageBundle, error) {
+	bundlesURL := "/image/getImageBundles.do?queryparam=&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, bundlesURL)
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Total        int           `json:"total"`
+		Data         []ImageBundle `json:"data"`
+		ErrorCode    string        `json:"errorCode"`
+		ErrorMessage string        `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListImageBundles :%s\n", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// GetImageBundleByName returns the image bundle with the given name
+// including its images
+func (c *CvpClient) GetImageBundleByName(ctx context.Context, name string) (ImageBundle, error) {
+	bundleURL := "/image/getImageBundleByName.do?name=" + url.QueryEscape(name)
+	respbody, err := c.GetWithContext(ctx, bundleURL)
+	if err != nil {
+		return ImageBundle{}, err
+	}
+	resp := struct {
+		ImageBundle
+		ErrorCode    string `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding GetImageBundleByName :%s\n", err)
+		return ImageBundle{}, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return ImageBundle{}, err
+	}
+	if resp.Key == "" {
+		return ImageBundle{}, fmt.Errorf("No image bundle named \"%s\" found", name)
+	}
+	return resp.ImageBundle, nil
+}
+
+// CreateImageBundle creates an image bundle out of the named images
+func (c *CvpClient) CreateImageBundle(ctx context.Context, name string, imageNames []string, certified bool) error {
+	all, err := c.ListImages(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]Image, len(all))
+	for _, image := range all {
+		byName[image.Name] = image
+	}
+	images := make([]Image, 0, len(imageNames))
+	for _, imageName := range imageNames {
+		image, ok := byName[imageName]
+		if !ok {
+			return fmt.Errorf("No image named \"%s\" found", imageName)
+		}
+		images = append(images, image)
+	}
+	url := "/image/saveImageBundle.do"
+	data := struct {
+		Name             string  `json:"name"`
+		IsCertifiedImage string  `json:"isCertifiedImage"`
+		Images           []Image `json:"images"`
+	}{name, fmt.Sprintf("%t", certified), images}
+	resp, err := c.CallWithContext(ctx, data, url)
+	if err != nil {
+		return err
+	}
+	responseBody := JsonData{}
+	if err = json.Unmarshal(resp, &responseBody); err != nil {
+		log.Printf("Error creating image bundle %+v", err)
+		return err
+	}
+	return checkErrors(responseBody)
+}
+
+// DeleteImageBundle deletes the image bundle with the given name
+func (c *CvpClient) DeleteImageBundle(ctx context.Context, name string) error {
+	bundle, err := c.GetImageBundleByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	url := "/image/deleteImageBundles.do"
+	data := JsonData{
+		Data: []DeleteConfiglet{{Key: bundle.Key, Name: bundle.Name}},
+	}
+	resp, err := c.CallWithContext(ctx, data, url)
+	if err != nil {
+		return err
+	}
+	responseBody := JsonData{}
+	if err = json.Unmarshal(resp, &responseBody); err != nil {
+		log.Printf("Error deleting image bundle %+v", err)
+		return err
+	}
+	return checkErrors(responseBody)
+}
+
+// ApplyImageBundle assigns an image bundle to a device or container. With
+// save set, a failure discards every unsaved temp action, see
+// CancelTopology. Without it the session is left as it is, the same holds
+// for RemoveImageBundle.
+func (c *CvpClient) ApplyImageBundle(ctx context.Context, bundleName string, target ImageBundleTarget, save bool) (SaveData, error) {
+	return c.imageBundleOp(ctx, bundleName, target, false, save)
+}
+
+// RemoveImageBundle removes an image bundle from a device or container
+func (c *CvpClient) RemoveImageBundle(ctx context.Context, bundleName string, target ImageBundleTarget, save bool) (SaveData, error) {
+	return c.imageBundleOp(ctx, bundleName, target, true, save)
+}
+
+func (c *CvpClient) imageBundleOp(ctx context.Context, bundleName string, target ImageBundleTarget, remove, save bool) (sdata SaveData, err error) {
+	bundle, err := c.GetImageBundleByName(ctx, bundleName)
+	if err != nil {
+		return sdata, err
+	}
+	action := Action{
+		Info:        "Image Bundle Assign to " + target.Type + ": " + target.Name,
+		InfoPreview: "<b>Image Bundle assign</b> to " + target.Name,
+		Action:      "associate",
+		NodeType:    "imagebundle",
+		NodeID:      bundle.Key,
+		NodeName:    bundle.Name,
+		ToID:        target.ID,
+		ToIDType:    target.Type,
+		ToName:      target.Name,
+	}
+	if remove {
+		action.Info = "Image Bundle Removal from " + target.Type + ": " + target.Name
+		action.InfoPreview = "<b>Image Bundle removal</b> from " + target.Name
+		action.NodeID = ""
+		action.NodeName = ""
+		action.IgnoreNodeID = bundle.Key
+		action.IgnoreNodeName = bundle.Name
+	}
+	log.Printf("Applying image bundle action : %+v", action)
+	if err = c.addTempAction(ctx, action); err != nil {
+		if save {
+			err = c.rollback(ctx, err)
+		}
+		return sdata, err
+	}
+	if save {
+		if sdata, err = c.saveTopologyV2(ctx, []string{}); err != nil {
+			return sdata, c.rollback(ctx, err)
+		}
+	}
+	return sdata, err
+}
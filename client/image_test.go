@@ -0,0 +1,56 @@
+package cvpgo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadImage(t *testing.T) {
+	path := os.Getenv("CVPGO_TEST_IMAGE")
+	if path == "" {
+		t.Skip("CVPGO_TEST_IMAGE is not set")
+	}
+	testdata := buildTestData()
+	cvpInfo := *testdata.CVP
+	cvp := New(cvpInfo.IPAddress, cvpInfo.Username, cvpInfo.Password)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer f.Close()
+	image, err := cvp.UploadImage(context.Background(), filepath.Base(path), f)
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+	t.Logf("Uploaded %+v", image)
+}
+
+func TestImageBundleLifecycle(t *testing.T) {
+	testdata := buildTestData()
+	cvpInfo := *testdata.CVP
+	cvp := New(cvpInfo.IPAddress, cvpInfo.Username, cvpInfo.Password)
+	ctx := context.Background()
+	images, err := cvp.ListImages(ctx)
+	if err != nil || len(images) == 0 {
+		t.Skipf("No images to work with : %v", err)
+	}
+	bundle := "cvpgo-test"
+	if err = cvp.CreateImageBundle(ctx, bundle, []string{images[0].Name}, false); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	container, err := cvp.GetContainerByName(testdata.DeviceContainer)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err = cvp.ApplyImageBundle(ctx, bundle, ContainerTarget(*container), true); err != nil {
+		t.Errorf("%+v", err)
+	}
+	if _, err = cvp.RemoveImageBundle(ctx, bundle, ContainerTarget(*container), true); err != nil {
+		t.Errorf("%+v", err)
+	}
+	if err = cvp.DeleteImageBundle(ctx, bundle); err != nil {
+		t.Errorf("%+v", err)
+	}
+}
@@ -0,0 +1,459 @@
+package cvpgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ConflictPolicy decides what Import does with an exported configlet whose
+// name is taken in CVP by a configlet with a different config
+type ConflictPolicy int
+
+const (
+	// ConflictSkip keeps the configlet in CVP
+	ConflictSkip ConflictPolicy = iota
+	// ConflictOverwrite replaces the config of the configlet in CVP
+	ConflictOverwrite
+	// ConflictRename imports the configlet under a new name, and assigns
+	// it under that name
+	ConflictRename
+)
+
+// defaultRenameSuffix is appended to the names of renamed configlets when
+// ImportOptions.RenameSuffix is not set
+const defaultRenameSuffix = "_imported"
+
+// ImportOptions controls Import
+type ImportOptions struct {
+	Policy       ConflictPolicy
+	RenameSuffix string
+	// DryRun only plans the import without changing anything in CVP
+	DryRun bool
+	// DeviceMap maps the serial numbers of exported devices to the serial
+	// numbers of devices in CVP, unmapped devices are matched by their
+	// own serial number
+	DeviceMap map[string]string
+}
+
+// Actions of an import step
+const (
+	ImportCreate    = "create"
+	ImportOverwrite = "overwrite"
+	ImportRename    = "rename"
+	ImportSkip      = "skip"
+	ImportMove      = "move"
+	ImportAssign    = "assign"
+)
+
+// Kinds of objects changed by an import step
+const (
+	ImportConfiglet = "configlet"
+	ImportContainer = "container"
+	ImportDevice    = "device"
+)
+
+// ImportStep is one change made by Import. Name is the name of the object
+// in the export and Target the name of the object in CVP, which differs
+// for renamed configlets and for devices.
+type ImportStep struct {
+	Action string
+	Kind   string
+	Name   string
+	Target string
+	Detail string
+}
+
+func (s ImportStep) String() string {
+	line := fmt.Sprintf("%-9s %-9s %s", s.Action, s.Kind, s.Name)
+	if s.Target != "" && s.Target != s.Name {
+		line += " as " + s.Target
+	}
+	if s.Detail != "" {
+		line += " (" + s.Detail + ")"
+	}
+	return line
+}
+
+// ImportPlan lists the steps of an import, in the order they are made, and
+// the IDs of the tasks they created
+type ImportPlan struct {
+	Steps   []ImportStep
+	TaskIds []string
+}
+
+func (p ImportPlan) String() string {
+	var b bytes.Buffer
+	for _, s := range p.Steps {
+		fmt.Fprintln(&b, s)
+	}
+	return b.String()
+}
+
+// ExportedConfigletFile is an exported configlet together with its config
+type ExportedConfigletFile struct {
+	ExportedConfiglet
+	Config string
+}
+
+// ExportedState is the content of a directory written by Export
+type ExportedState struct {
+	Configlets []ExportedConfigletFile
+	Containers []ExportedContainer
+	Devices    []ExportedDevice
+}
+
+// ReadExport reads a directory written by Export
+func ReadExport(dir string) (ExportedState, error) {
+	state := ExportedState{}
+	cfgletDir := filepath.Join(dir, ExportConfigletDir)
+	metas, err := filepath.Glob(filepath.Join(cfgletDir, "*.json"))
+	if err != nil {
+		return state, err
+	}
+	sort.Strings(metas)
+	for _, meta := range metas {
+		cfglet := ExportedConfigletFile{}
+		if err = readExportJSON(meta, &cfglet.ExportedConfiglet); err != nil {
+			return state, err
+		}
+		config, err := ioutil.ReadFile(strings.TrimSuffix(meta, ".json") + ".cfg")
+		if err != nil {
+			return state, err
+		}
+		cfglet.Config = string(config)
+		state.Configlets = append(state.Configlets, cfglet)
+	}
+	if err = readExportJSON(filepath.Join(dir, ExportContainerFile), &state.Containers); err != nil {
+		return state, err
+	}
+	if err = readExportJSON(filepath.Join(dir, ExportDeviceFile), &state.Devices); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+func readExportJSON(name string, v interface{}) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("Error decoding %s : %s", name, err)
+	}
+	return nil
+}
+
+// importer holds the state of one Import
+type importer struct {
+	c     *CvpClient
+	state ExportedState
+	opts  ImportOptions
+	plan  ImportPlan
+	// names maps exported configlet names to their names in CVP
+	names map[string]string
+}
+
+// Import recreates the configlets, containers and assignments of a
+// directory written by Export. Configlets missing from CVP are created and
+// configlets with a different config are handled according to
+// opts.Policy. Missing containers are created, devices are matched to the
+// inventory by serial number, moved to their exported container and
+// assigned their exported configlets. Devices missing from the inventory
+// are skipped. The returned plan lists every step, with opts.DryRun set
+// nothing is changed in CVP.
+func (c *CvpClient) Import(ctx context.Context, dir string, opts ImportOptions) (ImportPlan, error) {
+	state, err := ReadExport(dir)
+	if err != nil {
+		return ImportPlan{}, err
+	}
+	if opts.RenameSuffix == "" {
+		opts.RenameSuffix = defaultRenameSuffix
+	}
+	imp := &importer{c: c, state: state, opts: opts, names: make(map[string]string)}
+	if err = imp.configlets(ctx); err != nil {
+		return imp.plan, err
+	}
+	if err = imp.containers(ctx); err != nil {
+		return imp.plan, err
+	}
+	if err = imp.assignments(ctx); err != nil {
+		return imp.plan, err
+	}
+	return imp.plan, nil
+}
+
+func (imp *importer) add(step ImportStep) {
+	log.Printf("Import: %s", step)
+	imp.plan.Steps = append(imp.plan.Steps, step)
+}
+
+func (imp *importer) configlets(ctx context.Context) error {
+	current, _, err := imp.c.ListConfiglets(ctx, ConfigletFilter{})
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]Configlet, len(current))
+	for _, cfglet := range current {
+		byName[cfglet.Name] = cfglet
+	}
+	for _, cfglet := range imp.state.Configlets {
+		step := ImportStep{Action: ImportCreate, Kind: ImportConfiglet, Name: cfglet.Name, Target: cfglet.Name}
+		if live, ok := byName[cfglet.Name]; ok {
+			switch {
+			case SameConfig(live.Config, cfglet.Config):
+				step.Action, step.Detail = ImportSkip, "unchanged"
+			case imp.opts.Policy == ConflictOverwrite:
+				step.Action = ImportOverwrite
+			case imp.opts.Policy == ConflictRename:
+				step.Action = ImportRename
+				step.Target = cfglet.Name + imp.opts.RenameSuffix
+				for i := 2; byName[step.Target].Name != ""; i++ {
+					step.Target = fmt.Sprintf("%s%s%d", cfglet.Name, imp.opts.RenameSuffix, i)
+				}
+			default:
+				step.Action, step.Detail = ImportSkip, "config differs"
+			}
+		}
+		imp.names[cfglet.Name] = step.Target
+		byName[step.Target] = Configlet{Name: step.Target, Config: cfglet.Config}
+		imp.add(step)
+		if imp.opts.DryRun || step.Action == ImportSkip {
+			continue
+		}
+		_, ids, err := imp.c.UpsertConfiglet(ctx, Configlet{Name: step.Target, Config: cfglet.Config, Note: cfglet.Note})
+		if err != nil {
+			return err
+		}
+		imp.plan.TaskIds = append(imp.plan.TaskIds, ids...)
+	}
+	return nil
+}
+
+// containerLevels groups the exported containers by depth, parents first
+func (s ExportedState) containerLevels() [][]ExportedContainer {
+	parents := make(map[string]string, len(s.Containers))
+	for _, container := range s.Containers {
+		parents[container.Name] = container.Parent
+	}
+	var levels [][]ExportedContainer
+	for _, container := range s.Containers {
+		depth := 0
+		for p := container.Parent; p != "" && depth <= len(s.Containers); p = parents[p] {
+			depth++
+		}
+		for len(levels) <= depth {
+			levels = append(levels, nil)
+		}
+		levels[depth] = append(levels[depth], container)
+	}
+	return levels
+}
+
+func (imp *importer) containers(ctx context.Context) error {
+	for _, level := range imp.state.containerLevels() {
+		current, err := imp.c.ListContainers(ctx)
+		if err != nil {
+			return err
+		}
+		byName := make(map[string]Container, len(current))
+		for _, container := range current {
+			byName[container.Name] = container
+		}
+		var actions []Action
+		for _, container := range level {
+			if _, ok := byName[container.Name]; ok {
+				continue
+			}
+			if container.Parent == "" {
+				return fmt.Errorf("Root container \"%s\" does not exist", container.Name)
+			}
+			imp.add(ImportStep{Action: ImportCreate, Kind: ImportContainer, Name: container.Name, Target: container.Name, Detail: "in " + container.Parent})
+			parent, ok := byName[container.Parent]
+			if !ok && !imp.opts.DryRun {
+				return fmt.Errorf("No container named \"%s\" found", container.Parent)
+			}
+			actions = append(actions, NewContainerAction(container.Name, parent))
+		}
+		if imp.opts.DryRun || len(actions) == 0 {
+			continue
+		}
+		if err = imp.c.AddTempActions(ctx, actions); err != nil {
+			return err
+		}
+		if _, err = imp.c.SaveTopology(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assigned maps exported configlet names to configlets in CVP, keeping the
+// builders and generated configlets of current. It also returns the names
+// of the static configlets in CVP.
+func (imp *importer) assigned(current []Configlet, names []string, byName map[string]Configlet) ([]Configlet, []string, error) {
+	var configlets []Configlet
+	for _, cfglet := range current {
+		if !cfglet.IsStatic() {
+			configlets = append(configlets, cfglet)
+		}
+	}
+	var targets []string
+	for _, name := range names {
+		target := imp.names[name]
+		if target == "" {
+			target = name
+		}
+		cfglet, ok := byName[target]
+		if !ok && !imp.opts.DryRun {
+			return nil, nil, fmt.Errorf("No configlet named \"%s\" found", target)
+		}
+		targets = append(targets, target)
+		configlets = append(configlets, cfglet)
+	}
+	return configlets, targets, nil
+}
+
+// deviceConfiglets returns the configlets of an exported device that its
+// container and the containers above it do not already assign, so that
+// they are not assigned twice
+func (imp *importer) deviceConfiglets(dev ExportedDevice) []string {
+	byName := make(map[string]ExportedContainer, len(imp.state.Containers))
+	for _, container := range imp.state.Containers {
+		byName[container.Name] = container
+	}
+	inherited := make(map[string]bool)
+	name := dev.Container
+	for depth := 0; depth <= len(byName); depth++ {
+		container, ok := byName[name]
+		if !ok {
+			break
+		}
+		for _, cfglet := range container.Configlets {
+			inherited[cfglet] = true
+		}
+		name = container.Parent
+	}
+	var names []string
+	for _, cfglet := range dev.Configlets {
+		if !inherited[cfglet] {
+			names = append(names, cfglet)
+		}
+	}
+	return names
+}
+
+func sameNames(a, b []string) bool {
+	return strings.Join(a, "\n") == strings.Join(b, "\n")
+}
+
+func (imp *importer) assignments(ctx context.Context) error {
+	containers, err := imp.c.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+	containerByName := make(map[string]Container, len(containers))
+	containerByKey := make(map[string]Container, len(containers))
+	for _, container := range containers {
+		containerByName[container.Name] = container
+		containerByKey[container.Key] = container
+	}
+	configlets, _, err := imp.c.ListConfiglets(ctx, ConfigletFilter{})
+	if err != nil {
+		return err
+	}
+	cfgletByName := make(map[string]Configlet, len(configlets))
+	for _, cfglet := range configlets {
+		cfgletByName[cfglet.Name] = cfglet
+	}
+	devices, err := imp.c.ListDevices(ctx)
+	if err != nil {
+		return err
+	}
+	bySerial := make(map[string]NetElement, len(devices))
+	for _, dev := range devices {
+		bySerial[dev.SerialNumber] = dev
+	}
+
+	var actions []Action
+	for _, exported := range imp.state.Containers {
+		container, ok := containerByName[exported.Name]
+		var current []Configlet
+		if ok {
+			if current, err = imp.c.GetConfigletByContainerID(ctx, container.Key); err != nil {
+				return err
+			}
+		}
+		want, targets, err := imp.assigned(current, exported.Configlets, cfgletByName)
+		if err != nil {
+			return err
+		}
+		if have := staticConfigletNames(current); !sameNames(have, targets) {
+			imp.add(ImportStep{Action: ImportAssign, Kind: ImportContainer, Name: exported.Name, Target: exported.Name,
+				Detail: fmt.Sprintf("[%s] -> [%s]", strings.Join(have, " "), strings.Join(targets, " "))})
+			actions = append(actions, ContainerConfigletsAction(container, want))
+		}
+	}
+	// CVP's device lookup also returns the configlets a device inherits
+	inherited := make(map[string][]Configlet)
+	for _, exported := range imp.state.Devices {
+		serial := exported.SerialNumber
+		if mapped, ok := imp.opts.DeviceMap[serial]; ok {
+			serial = mapped
+		}
+		dev, ok := bySerial[serial]
+		if !ok || serial == "" {
+			imp.add(ImportStep{Action: ImportSkip, Kind: ImportDevice, Name: exported.Hostname, Detail: "serial number " + serial + " not in inventory"})
+			continue
+		}
+		from, ok := containerByKey[dev.ParentContainerKey]
+		if !ok {
+			return fmt.Errorf("No container with key \"%s\" found for device %s", dev.ParentContainerKey, dev.Fqdn)
+		}
+		if from.Name != exported.Container {
+			imp.add(ImportStep{Action: ImportMove, Kind: ImportDevice, Name: exported.Hostname, Target: dev.Fqdn, Detail: from.Name + " -> " + exported.Container})
+			to, ok := containerByName[exported.Container]
+			if !ok && !imp.opts.DryRun {
+				return fmt.Errorf("No container named \"%s\" found", exported.Container)
+			}
+			actions = append(actions, MoveDeviceAction(dev, from, to))
+		}
+		current, err := imp.c.GetConfigletByDeviceIDWithContext(ctx, dev.SystemMacAddress)
+		if err != nil {
+			return err
+		}
+		if _, ok = inherited[dev.ParentContainerKey]; !ok {
+			if inherited[dev.ParentContainerKey], err = imp.c.InheritedConfiglets(ctx, dev.ParentContainerKey); err != nil {
+				return err
+			}
+		}
+		current = WithoutConfiglets(current, inherited[dev.ParentContainerKey])
+		want, targets, err := imp.assigned(current, imp.deviceConfiglets(exported), cfgletByName)
+		if err != nil {
+			return err
+		}
+		if have := staticConfigletNames(current); !sameNames(have, targets) {
+			imp.add(ImportStep{Action: ImportAssign, Kind: ImportDevice, Name: exported.Hostname, Target: dev.Fqdn,
+				Detail: fmt.Sprintf("[%s] -> [%s]", strings.Join(have, " "), strings.Join(targets, " "))})
+			actions = append(actions, DeviceConfigletsAction(dev, want))
+		}
+	}
+	if imp.opts.DryRun || len(actions) == 0 {
+		return nil
+	}
+	if err = imp.c.AddTempActions(ctx, actions); err != nil {
+		return err
+	}
+	sdata, err := imp.c.SaveTopology(ctx)
+	if err != nil {
+		return err
+	}
+	imp.plan.TaskIds = append(imp.plan.TaskIds, sdata.Data.TaskIds...)
+	return nil
+}
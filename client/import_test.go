@@ -0,0 +1,84 @@
+package cvpgo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportDryRun(t *testing.T) {
+	srv := newExportTestServer()
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL, Client: srv.Client()}
+	dir, err := ioutil.TempDir("", "cvpgo-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = cvp.Export(context.Background(), dir); err != nil {
+		t.Fatalf("Error exporting : %s", err)
+	}
+	// an export listing the configlet leaf1 inherits from Leafs must not
+	// assign it to leaf1 again
+	devicesFile := filepath.Join(dir, ExportDeviceFile)
+	devices, _ := ioutil.ReadFile(devicesFile)
+	ioutil.WriteFile(devicesFile, []byte(strings.Replace(string(devices), `"leaf/intf"`, `"base", "leaf/intf"`, 1)), 0644)
+	state, err := ReadExport(dir)
+	if err != nil || len(state.Configlets) != 2 || state.Configlets[1].Config != "hostname leaf/intf\n" {
+		t.Fatalf("Unexpected export %+v (%v)", state, err)
+	}
+
+	// the test server lists configlets without their config, so every
+	// exported configlet conflicts with the one in CVP
+	tests := []struct {
+		opts ImportOptions
+		want []string
+	}{
+		{ImportOptions{DryRun: true}, []string{
+			"skip      configlet base (config differs)",
+			"skip      configlet leaf/intf (config differs)",
+		}},
+		{ImportOptions{DryRun: true, Policy: ConflictRename}, []string{
+			"rename    configlet base as base_imported",
+			"rename    configlet leaf/intf as leaf/intf_imported",
+			"assign    container Leafs ([base] -> [base_imported])",
+			"assign    device    leaf1 ([leaf/intf] -> [leaf/intf_imported])",
+		}},
+		{ImportOptions{DryRun: true, Policy: ConflictOverwrite, DeviceMap: map[string]string{"SN1": "SN9"}}, []string{
+			"overwrite configlet base",
+			"overwrite configlet leaf/intf",
+			"skip      device    leaf1 (serial number SN9 not in inventory)",
+		}},
+	}
+	for _, test := range tests {
+		plan, err := cvp.Import(context.Background(), dir, test.opts)
+		if err != nil {
+			t.Errorf("Error importing with %+v : %s", test.opts, err)
+			continue
+		}
+		if got := strings.TrimSpace(plan.String()); got != strings.Join(test.want, "\n") {
+			t.Errorf("Unexpected plan with %+v\n%s\nwant\n%s", test.opts, got, strings.Join(test.want, "\n"))
+		}
+	}
+
+	// a device in a container CVP does not list has no known source to be
+	// moved from
+	orphan := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/inventory/getInventory.do" {
+			fmt.Fprint(w, `{"netElementList":[{"fqdn":"leaf1","serialNumber":"SN1","systemMacAddress":"00:1c:73:00:00:01","parentContainerKey":"c9"}]}`)
+			return
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer orphan.Close()
+	cvp = CvpClient{BaseURL: orphan.URL, Client: orphan.Client()}
+	if _, err = cvp.Import(context.Background(), dir, ImportOptions{DryRun: true}); err == nil || !strings.Contains(err.Error(), "c9") {
+		t.Errorf("Expected an error for the unknown container, got %v", err)
+	}
+}
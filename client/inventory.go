@@ -1,6 +1,7 @@
 package cvpgo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -31,6 +32,9 @@ type GetContainer struct {
 type Container struct {
 	Name string `json:"name"`
 	Key  string `json:"key"`
+	// ParentName is only set by ListContainers, it is empty for the root
+	// container
+	ParentName string `json:"parentName,omitempty"`
 }
 
 type NetElement struct {
@@ -42,6 +46,13 @@ type NetElement struct {
 	Fqdn             string `json:"fqdn"`
 	Key              string `json:"key"`
 	IPAddress        string `json:"ipAddress"`
+	Status           string `json:"status,omitempty"`
+	StreamingStatus  string `json:"streamingStatus,omitempty"`
+	// ParentContainerKey is the key of the container holding the device
+	ParentContainerKey string `json:"parentContainerKey,omitempty"`
+	// ComplianceCode is CVP's last compliance check result, see
+	// ComplianceCodes
+	ComplianceCode string `json:"complianceCode,omitempty"`
 }
 
 type TempNetElement struct {
@@ -237,8 +248,17 @@ func (c *CvpClient) CancelTempInventory() error {
 // GetDevice uses the unique ID of a device to lookup the full entry in CVP
 // and returns the full NetElement entry
 func (c *CvpClient) GetDevice(id string) (*NetElement, error) {
+	return c.GetDeviceWithContext(context.Background(), id)
+}
+
+// GetDeviceWithContext is GetDevice with a context, it returns the first
+// device matching id
+func (c *CvpClient) GetDeviceWithContext(ctx context.Context, id string) (*NetElement, error) {
 	getDeviceURL := "/inventory/getInventory.do?queryparam=" + url.QueryEscape(id) + "&startIndex=0&endIndex=0"
-	respbody, err := c.Get(getDeviceURL)
+	respbody, err := c.GetWithContext(ctx, getDeviceURL)
+	if err != nil {
+		return nil, err
+	}
 	respDevice := GetInventory{}
 	err = json.Unmarshal(respbody, &respDevice)
 	if err != nil {
@@ -267,6 +287,29 @@ func (c *CvpClient) GetContainerByName(query string) (*Container, error) {
 	return &respContainer.ContainerList[0], err
 }
 
+// ListContainers returns all containers, including the root container
+func (c *CvpClient) ListContainers(ctx context.Context) ([]Container, error) {
+	containersURL := "/inventory/add/searchContainers.do?queryparam=&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, containersURL)
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Total        int         `json:"total"`
+		Data         []Container `json:"data"`
+		ErrorCode    string      `json:"errorCode"`
+		ErrorMessage string      `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListContainers :%s\n", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
 // Returns a container name based on its ID
 func (c *CvpClient) GetContainerNameById(query string) (string, error) {
 	url := "/provisioning/getContainerInfoById.do?containerId=" + query
@@ -284,18 +327,35 @@ func (c *CvpClient) GetContainerNameById(query string) (string, error) {
 
 // GetInventory will return all the devices in CVP
 func (c *CvpClient) GetInventory(query string) (*[]NetElement, error) {
-	getDeviceURL := "/inventory/getInventory.do?queryparam=" + query + "&startIndex=0&endIndex=0"
-	respbody, err := c.Get(getDeviceURL)
-	respDevice := GetInventory{}
-	err = json.Unmarshal(respbody, &respDevice)
+	devices, err := c.inventory(context.Background(), query)
 	if err != nil {
-		log.Printf("Error decoding getdevice :%s\n", err)
 		return nil, err
 	}
-	if len(respDevice.NetElementList) == 0 {
+	if len(devices) == 0 {
 		return nil, fmt.Errorf("No devices returned")
 	}
-	return &respDevice.NetElementList, err
+	return &devices, nil
+}
+
+// ListDevices returns all devices in the inventory
+func (c *CvpClient) ListDevices(ctx context.Context) ([]NetElement, error) {
+	return c.inventory(ctx, "")
+}
+
+// inventory returns the devices matching query, an empty inventory is not
+// an error
+func (c *CvpClient) inventory(ctx context.Context, query string) ([]NetElement, error) {
+	getDeviceURL := "/inventory/getInventory.do?queryparam=" + query + "&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, getDeviceURL)
+	if err != nil {
+		return nil, err
+	}
+	respDevice := GetInventory{}
+	if err = json.Unmarshal(respbody, &respDevice); err != nil {
+		log.Printf("Error decoding getdevice :%s\n", err)
+		return nil, err
+	}
+	return respDevice.NetElementList, nil
 }
 
 func (c *CvpClient) AddContainerToRoot(new string) error {
@@ -307,6 +367,8 @@ func (c *CvpClient) AddContainerToRoot(new string) error {
 	return c.AddContainer(new, name)
 }
 
+// AddContainer creates a container below parent and saves it. A failure
+// discards every unsaved temp action, see CancelTopology.
 func (c *CvpClient) AddContainer(new, parent string) error {
 	newC := &Container{
 		Name: new,
@@ -326,6 +388,8 @@ func (c *CvpClient) AddContainer(new, parent string) error {
 	return nil
 }
 
+// DeleteContainer deletes a container and saves it. A failure discards
+// every unsaved temp action, see CancelTopology.
 func (c *CvpClient) DeleteContainer(name, parent string) error {
 	currentC, err := c.GetContainerByName(name)
 	if err != nil {
@@ -346,6 +410,7 @@ func (c *CvpClient) DeleteContainer(name, parent string) error {
 	return nil
 }
 
+// containerOp always saves, so a failed step discards the whole session
 func (c *CvpClient) containerOp(container, parent *Container, op string) (sdata SaveData, err error) {
 	info := "Performing " + op + " operation on container " + container.Name
 	data := Action{
@@ -367,9 +432,12 @@ func (c *CvpClient) containerOp(container, parent *Container, op string) (sdata
 		data.FromName = parent.Name
 	}
 	log.Printf("Operation data read: %+v", data)
-	if err = c.addTempAction(data); err != nil {
-		return sdata, err
+	ctx := context.Background()
+	if err = c.addTempAction(ctx, data); err != nil {
+		return sdata, c.rollback(ctx, err)
 	}
-
-	return c.saveTopologyV2([]string{})
+	if sdata, err = c.saveTopologyV2(ctx, []string{}); err != nil {
+		return sdata, c.rollback(ctx, err)
+	}
+	return sdata, nil
 }
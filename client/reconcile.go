@@ -0,0 +1,95 @@
+package cvpgo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ReconcileResult describes the outcome of ReconcileDevice
+type ReconcileResult struct {
+	DeviceID      string
+	ConfigletName string
+	ConfigletKey  string
+	// Config is the running config that is missing from the designed
+	// config, it is empty when the device is in sync
+	Config  string
+	InSync  bool
+	Applied bool
+	TaskIds []string
+}
+
+// Lines returns the number of config lines that were reconciled
+func (r ReconcileResult) Lines() int {
+	if r.Config == "" {
+		return 0
+	}
+	return len(strings.Split(strings.TrimRight(r.Config, "\n"), "\n"))
+}
+
+// ReconcileDevice compares the designed config of a device against its
+// running config and captures the differences in the device's reconcile
+// configlet, which is created if needed and assigned to the device.
+// With dryRun set, only the reconcile config is generated and nothing is
+// changed in CVP.
+func (c *CvpClient) ReconcileDevice(ctx context.Context, deviceID string, dryRun bool) (ReconcileResult, error) {
+	result := ReconcileResult{DeviceID: deviceID}
+	dev, err := c.GetDeviceWithContext(ctx, deviceID)
+	if err != nil {
+		return result, err
+	}
+	assigned, err := c.GetConfigletByDeviceIDWithContext(ctx, deviceID)
+	if err != nil {
+		return result, err
+	}
+	var designed []Configlet
+	var reconcile *Configlet
+	for i, cfglet := range assigned {
+		if cfglet.Reconciled {
+			reconcile = &assigned[i]
+			continue
+		}
+		designed = append(designed, cfglet)
+	}
+	validation, err := c.validateCompareCfglt(ctx, deviceID, getKeys(designed))
+	if err != nil {
+		return result, err
+	}
+	if validation.ErrorMsg != "" {
+		return result, fmt.Errorf("Error comparing configs of %s : %s", deviceID, validation.ErrorMsg)
+	}
+	result.Config = validation.ReconciledConfig.Config
+	result.ConfigletName = validation.ReconciledConfig.Name
+	if result.ConfigletName == "" {
+		result.ConfigletName = "RECONCILE_" + dev.IPAddress
+	}
+	if reconcile != nil {
+		result.ConfigletKey = reconcile.Key
+	}
+	if strings.TrimSpace(result.Config) == "" {
+		result.InSync = true
+		return result, nil
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	log.Printf("Reconciling %d lines on %s", result.Lines(), deviceID)
+	stored, err := c.updateReconcile(ctx, deviceID, result.ConfigletName, result.Config)
+	if err != nil {
+		return result, err
+	}
+	if stored.Key != "" {
+		result.ConfigletKey = stored.Key
+	}
+	if reconcile == nil {
+		sdata, err := c.applyConfigletToDevice(ctx, dev.IPAddress, dev.Fqdn, deviceID, []string{result.ConfigletName}, true)
+		if err != nil {
+			return result, err
+		}
+		result.TaskIds = sdata.Data.TaskIds
+	}
+	result.Applied = true
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package cvpgo
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReconcileDeviceDryRun(t *testing.T) {
+	data := buildConfigletTestData()
+	t.Logf("Test data: %+v", data)
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	result, err := cvp.ReconcileDevice(context.Background(), data.NetElementID, true)
+	if err != nil {
+		t.Errorf("Error reconciling device : %s", err)
+	}
+	if result.Applied {
+		t.Errorf("Dry run must not apply the reconcile configlet")
+	}
+	t.Logf("Reconcile would capture %d lines", result.Lines())
+}
+
+func TestReconcileDevice(t *testing.T) {
+	data := buildConfigletTestData()
+	t.Logf("Test data: %+v", data)
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	result, err := cvp.ReconcileDevice(context.Background(), data.NetElementID, false)
+	if err != nil {
+		t.Errorf("Error reconciling device : %s", err)
+	}
+	if !result.InSync && !result.Applied {
+		t.Errorf("Reconcile configlet %s was not applied", result.ConfigletName)
+	}
+}
@@ -0,0 +1,185 @@
+package cvpgo
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// resourceError is the body CVP's resource API returns on failure
+type resourceError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func checkResourceError(body []byte) error {
+	resp := resourceError{}
+	// streamed responses hold several objects, an error is always the
+	// first and only one
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&resp); err == io.EOF {
+		return nil
+	} else if err != nil {
+		log.Printf("Error decoding CVP response :%s\n", err)
+		return fmt.Errorf("Invalid response from CVP: %s (%s)", err, body)
+	}
+	if resp.Code != 0 && resp.Message != "" {
+		log.Printf("Error from CVP: %s", resp.Message)
+		return fmt.Errorf("CVP returned error code: %d, %s", resp.Code, resp.Message)
+	}
+	return nil
+}
+
+// resourcePost sets a resource in CVP's resource API
+func (c *CvpClient) resourcePost(ctx context.Context, path string, obj interface{}) error {
+	resp, err := c.post(ctx, obj, c.resourceURL(path))
+	if err != nil {
+		return err
+	}
+	return checkResourceError(resp)
+}
+
+// resourceDelete deletes a resource in CVP's resource API, path holds the
+// key of the resource as query parameters
+func (c *CvpClient) resourceDelete(ctx context.Context, path string) error {
+	resp, err := c.do(ctx, "DELETE", c.resourceURL(path))
+	if err != nil {
+		return err
+	}
+	return checkResourceError(resp)
+}
+
+// resourceGetAll fetches every resource below path and calls fn with the
+// value of each, the resource API streams them as consecutive JSON objects
+func (c *CvpClient) resourceGetAll(ctx context.Context, path string, fn func(value json.RawMessage) error) error {
+	resp, err := c.get(ctx, c.resourceURL(path))
+	if err != nil {
+		return err
+	}
+	if err = checkResourceError(resp); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(resp))
+	for {
+		item := struct {
+			Result struct {
+				Value json.RawMessage `json:"value"`
+			} `json:"result"`
+		}{}
+		if err = dec.Decode(&item); err == io.EOF {
+			return nil
+		} else if err != nil {
+			log.Printf("Error decoding %s :%s\n", path, err)
+			return err
+		}
+		if len(item.Result.Value) == 0 {
+			continue
+		}
+		if err = fn(item.Result.Value); err != nil {
+			return err
+		}
+	}
+}
+
+func newResourceID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// inWorkspace runs fn inside a new workspace and submits the workspace
+// when fn succeeds, changes to studio backed resources such as tags can
+// only be made this way. The workspace is abandoned if any step fails.
+func (c *CvpClient) inWorkspace(ctx context.Context, name string, fn func(workspaceID string) error) (err error) {
+	workspaceID := newResourceID()
+	workspace := map[string]interface{}{
+		"key":         map[string]string{"workspaceId": workspaceID},
+		"displayName": name,
+	}
+	if err = c.resourcePost(ctx, workspacePath, workspace); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = c.abandonWorkspace(workspaceID, err)
+		}
+	}()
+	if err = fn(workspaceID); err != nil {
+		return err
+	}
+	requestID := newResourceID()
+	if err = c.resourcePost(ctx, workspacePath, workspaceRequest(workspaceID, "REQUEST_SUBMIT", requestID)); err != nil {
+		return err
+	}
+	return c.waitForWorkspace(ctx, workspaceID, requestID)
+}
+
+const workspacePath = "/api/resources/workspace/v1/WorkspaceConfig"
+
+func workspaceRequest(workspaceID, request, requestID string) map[string]interface{} {
+	return map[string]interface{}{
+		"key":           map[string]string{"workspaceId": workspaceID},
+		"request":       request,
+		"requestParams": map[string]string{"requestId": requestID},
+	}
+}
+
+// abandonWorkspace abandons a workspace after cause made it fail. It does
+// not use the caller's context, which may be the cause. It returns cause,
+// annotated if abandoning failed too.
+func (c *CvpClient) abandonWorkspace(workspaceID string, cause error) error {
+	log.Printf("Abandoning workspace %s after error : %s", workspaceID, cause)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := c.resourcePost(ctx, workspacePath, workspaceRequest(workspaceID, "REQUEST_ABANDON", newResourceID())); err != nil {
+		return fmt.Errorf("%s (abandoning workspace %s also failed: %s)", cause, workspaceID, err)
+	}
+	return cause
+}
+
+// waitForWorkspace polls a workspace until CVP has answered the request
+func (c *CvpClient) waitForWorkspace(ctx context.Context, workspaceID, requestID string) error {
+	url := c.resourceURL("/api/resources/workspace/v1/Workspace?key.workspaceId=" + workspaceID)
+	for {
+		respbody, err := c.get(ctx, url)
+		if err != nil {
+			return err
+		}
+		if err = checkResourceError(respbody); err != nil {
+			return err
+		}
+		resp := struct {
+			Value struct {
+				State     string `json:"state"`
+				Responses struct {
+					Values map[string]struct {
+						Status  string `json:"status"`
+						Message string `json:"message"`
+					} `json:"values"`
+				} `json:"responses"`
+			} `json:"value"`
+		}{}
+		if err = json.Unmarshal(respbody, &resp); err != nil {
+			log.Printf("Error decoding workspace :%s\n", err)
+			return err
+		}
+		if r, ok := resp.Value.Responses.Values[requestID]; ok {
+			if r.Status != "RESPONSE_STATUS_SUCCESS" {
+				return fmt.Errorf("Workspace %s was not submitted : %s", workspaceID, r.Message)
+			}
+			return nil
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SnapshotTemplate is a set of show commands CVP captures in a snapshot
+type SnapshotTemplate struct {
+	Key       string   `json:"key"`
+	Name      string   `json:"name"`
+	Commands  []string `json:"commands"`
+	Type      string   `json:"type"`
+	CreatedBy string   `json:"createdBy"`
+}
+
+// Snapshot is the output of a snapshot template captured on one device
+type Snapshot struct {
+	Key              string           `json:"key"`
+	TemplateKey      string           `json:"templateId"`
+	DeviceID         string           `json:"netElementId"`
+	CreatedTimestamp int64            `json:"createdTimestamp"`
+	Outputs          []SnapshotOutput `json:"commands"`
+}
+
+// SnapshotOutput is the output of one command of a snapshot
+type SnapshotOutput struct {
+	Command string `json:"command"`
+	Output  string `json:"output"`
+}
+
+// Created returns the time the snapshot was captured
+func (s Snapshot) Created() time.Time {
+	return time.Unix(0, s.CreatedTimestamp*int64(time.Millisecond))
+}
+
+// Output returns the output of command, or false if it was not captured
+func (s Snapshot) Output(command string) (string, bool) {
+	for _, o := range s.Outputs {
+		if o.Command == command {
+			return o.Output, true
+		}
+	}
+	return "", false
+}
+
+// SnapshotDiff is the difference in the output of one command between two
+// snapshots
+type SnapshotDiff struct {
+	Command string
+	Diff    ConfigDiff
+}
+
+// ListSnapshotTemplates returns all snapshot templates
+func (c *CvpClient) ListSnapshotTemplates(ctx context.Context) ([]SnapshotTemplate, error) {
+	templatesURL := "/snapshot/getSnapshotTemplates.do?queryparam=&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, templatesURL)
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Total        int                `json:"total"`
+		Data         []SnapshotTemplate `json:"data"`
+		ErrorCode    string             `json:"errorCode"`
+		ErrorMessage string             `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListSnapshotTemplates :%s\n", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// GetSnapshotTemplateByName returns the template with exactly the given name
+func (c *CvpClient) GetSnapshotTemplateByName(ctx context.Context, name string) (SnapshotTemplate, error) {
+	templates, err := c.ListSnapshotTemplates(ctx)
+	if err != nil {
+		return SnapshotTemplate{}, err
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return SnapshotTemplate{}, fmt.Errorf("No snapshot template named \"%s\" found", name)
+}
+
+// ListSnapshots returns the snapshots of a template captured on a device,
+// most recent first
+func (c *CvpClient) ListSnapshots(ctx context.Context, templateKey, deviceID string) ([]Snapshot, error) {
+	snapshotsURL := "/snapshot/getDeviceSnapshots.do?netElementId=" + url.QueryEscape(deviceID) +
+		"&templateId=" + url.QueryEscape(templateKey) + "&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, snapshotsURL)
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Total        int        `json:"total"`
+		Data         []Snapshot `json:"data"`
+		ErrorCode    string     `json:"errorCode"`
+		ErrorMessage string     `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListSnapshots :%s\n", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// CaptureSnapshot captures a snapshot of the template on every device in
+// deviceIDs and waits for the captures to show up, polling every second
+// until ctx is done. The snapshots are returned keyed by device ID.
+func (c *CvpClient) CaptureSnapshot(ctx context.Context, templateKey string, deviceIDs []string) (map[string]Snapshot, error) {
+	url := "/snapshot/captureSnapshot.do"
+	data := struct {
+		TemplateID string   `json:"templateId"`
+		DeviceList []string `json:"deviceList"`
+	}{templateKey, deviceIDs}
+	started := time.Now()
+	resp, err := c.CallWithContext(ctx, data, url)
+	if err != nil {
+		return nil, err
+	}
+	responseBody := JsonData{}
+	if err = json.Unmarshal(resp, &responseBody); err != nil {
+		log.Printf("Error capturing snapshot %+v", err)
+		return nil, err
+	}
+	if err = checkErrors(responseBody); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]Snapshot, len(deviceIDs))
+	for {
+		for _, id := range deviceIDs {
+			if _, ok := result[id]; ok {
+				continue
+			}
+			snapshots, err := c.ListSnapshots(ctx, templateKey, id)
+			if err != nil {
+				return result, err
+			}
+			// allow for some clock skew between us and CVP
+			for _, s := range snapshots {
+				if s.Created().After(started.Add(-time.Minute)) && len(s.Outputs) > 0 {
+					result[id] = s
+					break
+				}
+			}
+		}
+		if len(result) == len(deviceIDs) {
+			return result, nil
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return result, fmt.Errorf("Snapshots of %d devices were not captured : %s", len(deviceIDs)-len(result), ctx.Err())
+		}
+	}
+}
+
+// CompareSnapshots diffs the output of every command captured in before
+// and after, a command missing from one of them is compared against an
+// empty output
+func CompareSnapshots(before, after Snapshot) []SnapshotDiff {
+	var commands []string
+	seen := make(map[string]bool)
+	for _, s := range []Snapshot{before, after} {
+		for _, o := range s.Outputs {
+			if !seen[o.Command] {
+				seen[o.Command] = true
+				commands = append(commands, o.Command)
+			}
+		}
+	}
+	diffs := make([]SnapshotDiff, 0, len(commands))
+	for _, command := range commands {
+		old, _ := before.Output(command)
+		new, _ := after.Output(command)
+		diff := diffLines("before: "+command, "after: "+command, outputLines(old), outputLines(new))
+		diffs = append(diffs, SnapshotDiff{Command: command, Diff: diff})
+	}
+	return diffs
+}
+
+func outputLines(output string) []string {
+	output = strings.TrimRight(strings.Replace(output, "\r\n", "\n", -1), "\n")
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
@@ -0,0 +1,47 @@
+package cvpgo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	before := Snapshot{Outputs: []SnapshotOutput{
+		{Command: "show version", Output: "EOS 4.20\n"},
+		{Command: "show ip route", Output: "10.0.0.0/8\n192.168.0.0/16\n"},
+	}}
+	after := Snapshot{Outputs: []SnapshotOutput{
+		{Command: "show version", Output: "EOS 4.20\n"},
+		{Command: "show ip route", Output: "10.0.0.0/8\n"},
+		{Command: "show lldp neighbors", Output: "Ethernet1 spine1\n"},
+	}}
+	diffs := CompareSnapshots(before, after)
+	if len(diffs) != 3 {
+		t.Fatalf("Expected 3 commands, got %+v", diffs)
+	}
+	if diffs[0].Diff.HasChanges() {
+		t.Errorf("Unexpected change in %s", diffs[0].Command)
+	}
+	if diffs[1].Diff.Removed != 1 || diffs[2].Diff.Added != 1 {
+		t.Errorf("Unexpected diffs %+v", diffs)
+	}
+}
+
+func TestCaptureSnapshot(t *testing.T) {
+	data := buildConfigletTestData()
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	templates, err := cvp.ListSnapshotTemplates(ctx)
+	if err != nil || len(templates) == 0 {
+		t.Skipf("No snapshot templates to work with : %v", err)
+	}
+	snapshots, err := cvp.CaptureSnapshot(ctx, templates[0].Key, []string{data.NetElementID})
+	if err != nil {
+		t.Errorf("Error capturing snapshot : %s", err)
+	}
+	if _, ok := snapshots[data.NetElementID]; !ok {
+		t.Errorf("No snapshot captured for %s", data.NetElementID)
+	}
+}
@@ -0,0 +1,225 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Element types a tag can be attached to
+const (
+	TagElementDevice    = "ELEMENT_TYPE_DEVICE"
+	TagElementInterface = "ELEMENT_TYPE_INTERFACE"
+)
+
+// Tag is a label/value pair such as role:leaf
+type Tag struct {
+	ElementType string `json:"elementType"`
+	Label       string `json:"label"`
+	Value       string `json:"value"`
+}
+
+func (t Tag) String() string {
+	return t.Label + ":" + t.Value
+}
+
+// TagAssignment is a tag attached to a device, or to an interface of a
+// device when InterfaceID is set. Devices are identified by serial number.
+type TagAssignment struct {
+	Tag
+	DeviceID    string `json:"deviceId,omitempty"`
+	InterfaceID string `json:"interfaceId,omitempty"`
+}
+
+// TaggedDevice is a device in the inventory together with its device tags
+type TaggedDevice struct {
+	NetElement
+	Tags []Tag
+}
+
+// HasTag reports whether the device carries the tag, an empty value
+// matches any value of the label
+func (d TaggedDevice) HasTag(label, value string) bool {
+	for _, t := range d.Tags {
+		if t.Label == label && (value == "" || t.Value == value) {
+			return true
+		}
+	}
+	return false
+}
+
+type tagKey struct {
+	WorkspaceID string `json:"workspaceId"`
+	TagAssignment
+}
+
+// ListTags returns the tags defined for the given element type
+func (c *CvpClient) ListTags(ctx context.Context, elementType string) ([]Tag, error) {
+	var tags []Tag
+	err := c.resourceGetAll(ctx, "/api/resources/tag/v2/Tag/all", func(value json.RawMessage) error {
+		item := struct {
+			Key tagKey `json:"key"`
+		}{}
+		if err := json.Unmarshal(value, &item); err != nil {
+			return err
+		}
+		if item.Key.WorkspaceID == "" && (elementType == "" || item.Key.ElementType == elementType) {
+			tags = append(tags, item.Key.Tag)
+		}
+		return nil
+	})
+	return tags, err
+}
+
+// ListTagAssignments returns the tags attached to elements of the given type
+func (c *CvpClient) ListTagAssignments(ctx context.Context, elementType string) ([]TagAssignment, error) {
+	var assignments []TagAssignment
+	err := c.resourceGetAll(ctx, "/api/resources/tag/v2/TagAssignment/all", func(value json.RawMessage) error {
+		item := struct {
+			Key tagKey `json:"key"`
+		}{}
+		if err := json.Unmarshal(value, &item); err != nil {
+			return err
+		}
+		if item.Key.WorkspaceID == "" && (elementType == "" || item.Key.ElementType == elementType) {
+			assignments = append(assignments, item.Key.TagAssignment)
+		}
+		return nil
+	})
+	return assignments, err
+}
+
+// CreateTag defines a new tag
+func (c *CvpClient) CreateTag(ctx context.Context, tag Tag) error {
+	return c.tagConfig(ctx, "Create tag "+tag.String(), "/api/resources/tag/v2/TagConfig", TagAssignment{Tag: tag}, false)
+}
+
+// DeleteTag removes a tag definition
+func (c *CvpClient) DeleteTag(ctx context.Context, tag Tag) error {
+	return c.tagConfig(ctx, "Delete tag "+tag.String(), "/api/resources/tag/v2/TagConfig", TagAssignment{Tag: tag}, true)
+}
+
+// AssignTag attaches a tag to the device with the given serial number, or
+// to one of its interfaces when interfaceID is not empty
+func (c *CvpClient) AssignTag(ctx context.Context, tag Tag, deviceID, interfaceID string) error {
+	assignment := TagAssignment{Tag: tag, DeviceID: deviceID, InterfaceID: interfaceID}
+	return c.tagConfig(ctx, "Assign tag "+tag.String(), "/api/resources/tag/v2/TagAssignmentConfig", assignment, false)
+}
+
+// UnassignTag detaches a tag from a device or one of its interfaces
+func (c *CvpClient) UnassignTag(ctx context.Context, tag Tag, deviceID, interfaceID string) error {
+	assignment := TagAssignment{Tag: tag, DeviceID: deviceID, InterfaceID: interfaceID}
+	return c.tagConfig(ctx, "Unassign tag "+tag.String(), "/api/resources/tag/v2/TagAssignmentConfig", assignment, true)
+}
+
+func (c *CvpClient) tagConfig(ctx context.Context, name, path string, assignment TagAssignment, remove bool) error {
+	if assignment.ElementType == "" {
+		assignment.ElementType = TagElementDevice
+		if assignment.InterfaceID != "" {
+			assignment.ElementType = TagElementInterface
+		}
+	}
+	return c.inWorkspace(ctx, name, func(workspaceID string) error {
+		data := struct {
+			Key    tagKey `json:"key"`
+			Remove bool   `json:"remove,omitempty"`
+		}{tagKey{workspaceID, assignment}, remove}
+		return c.resourcePost(ctx, path, data)
+	})
+}
+
+// GetDevicesByTag returns the devices in the inventory whose tags match
+// expr. An expression is made of label:value terms, terms separated by
+// spaces must all match and groups of terms separated by OR are
+// alternatives. A term with only a label matches any value and a leading !
+// negates a term, e.g. "role:leaf pod:1 OR role:spine !rack:r9".
+func (c *CvpClient) GetDevicesByTag(ctx context.Context, expr string) ([]TaggedDevice, error) {
+	query, err := parseTagExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	inventory, err := c.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := c.ListTagAssignments(ctx, TagElementDevice)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string][]Tag)
+	for _, a := range assignments {
+		if a.InterfaceID == "" {
+			tags[a.DeviceID] = append(tags[a.DeviceID], a.Tag)
+		}
+	}
+	var devices []TaggedDevice
+	for _, dev := range inventory {
+		tagged := TaggedDevice{NetElement: dev, Tags: tags[dev.SerialNumber]}
+		if query.matches(tagged) {
+			devices = append(devices, tagged)
+		}
+	}
+	return devices, nil
+}
+
+type tagTerm struct {
+	label  string
+	value  string
+	negate bool
+}
+
+// tagExpr is a tag expression in disjunctive normal form
+type tagExpr [][]tagTerm
+
+func parseTagExpr(expr string) (tagExpr, error) {
+	var result tagExpr
+	var group []tagTerm
+	for _, field := range strings.Fields(expr) {
+		switch field {
+		case "OR":
+			if len(group) == 0 {
+				return nil, fmt.Errorf("Invalid tag expression \"%s\": empty alternative", expr)
+			}
+			result = append(result, group)
+			group = nil
+			continue
+		case "AND":
+			continue
+		}
+		term := tagTerm{}
+		if strings.HasPrefix(field, "!") {
+			term.negate = true
+			field = field[1:]
+		}
+		parts := strings.SplitN(field, ":", 2)
+		term.label = parts[0]
+		if len(parts) == 2 {
+			term.value = parts[1]
+		}
+		if term.label == "" {
+			return nil, fmt.Errorf("Invalid tag expression \"%s\": missing label", expr)
+		}
+		group = append(group, term)
+	}
+	if len(group) == 0 {
+		return nil, fmt.Errorf("Invalid tag expression \"%s\": empty alternative", expr)
+	}
+	return append(result, group), nil
+}
+
+func (e tagExpr) matches(dev TaggedDevice) bool {
+	for _, group := range e {
+		matched := true
+		for _, term := range group {
+			if dev.HasTag(term.label, term.value) == term.negate {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTagExpr(t *testing.T) {
+	leaf := TaggedDevice{Tags: []Tag{{Label: "role", Value: "leaf"}, {Label: "pod", Value: "1"}, {Label: "rack", Value: "r9"}}}
+	spine := TaggedDevice{Tags: []Tag{{Label: "role", Value: "spine"}}}
+	cases := []struct {
+		expr  string
+		leaf  bool
+		spine bool
+	}{
+		{"role:leaf", true, false},
+		{"role:leaf pod:2", false, false},
+		{"role:leaf AND pod:1", true, false},
+		{"role:leaf OR role:spine", true, true},
+		{"role !rack:r9", false, true},
+		{"pod", true, false},
+	}
+	for _, tc := range cases {
+		expr, err := parseTagExpr(tc.expr)
+		if err != nil {
+			t.Errorf("Error parsing %s : %s", tc.expr, err)
+			continue
+		}
+		if expr.matches(leaf) != tc.leaf || expr.matches(spine) != tc.spine {
+			t.Errorf("Unexpected matches for %s", tc.expr)
+		}
+	}
+	for _, invalid := range []string{"", "OR role:leaf", "role:leaf OR", ":leaf"} {
+		if _, err := parseTagExpr(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestAssignTag(t *testing.T) {
+	testdata := buildTestData()
+	cvpInfo := *testdata.CVP
+	cvp := New(cvpInfo.IPAddress, cvpInfo.Username, cvpInfo.Password)
+	ctx := context.Background()
+	dev, err := cvp.GetDevice(testdata.DeviceHostname)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	tag := Tag{Label: "role", Value: "cvpgo-test"}
+	if err = cvp.CreateTag(ctx, tag); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err = cvp.AssignTag(ctx, tag, dev.SerialNumber, ""); err != nil {
+		t.Errorf("%+v", err)
+	}
+	devices, err := cvp.GetDevicesByTag(ctx, tag.String())
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+	if len(devices) != 1 || devices[0].SerialNumber != dev.SerialNumber {
+		t.Errorf("Unexpected tagged devices %+v", devices)
+	}
+	if err = cvp.UnassignTag(ctx, tag, dev.SerialNumber, ""); err != nil {
+		t.Errorf("%+v", err)
+	}
+	if err = cvp.DeleteTag(ctx, tag); err != nil {
+		t.Errorf("%+v", err)
+	}
+}
+
+func TestAssignTagAbandonsWorkspace(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/resources/workspace/v1/WorkspaceConfig":
+			body := struct {
+				Request string `json:"request"`
+			}{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Request != "" {
+				requests = append(requests, body.Request)
+			}
+			fmt.Fprint(w, `{"value":{}}`)
+		case "/api/resources/tag/v2/TagAssignmentConfig":
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, "bad gateway")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL + "/cvpservice", Client: srv.Client()}
+	err := cvp.AssignTag(context.Background(), Tag{Label: "role", Value: "leaf"}, "SN1", "")
+	if err == nil || !strings.Contains(err.Error(), "bad gateway") {
+		t.Errorf("Expected the error body to be reported, got %v", err)
+	}
+	if strings.Join(requests, ",") != "REQUEST_ABANDON" {
+		t.Errorf("Unexpected workspace requests %v", requests)
+	}
+}
+
+func TestAssignTagWorkspaceError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/resources/workspace/v1/WorkspaceConfig", "/api/resources/tag/v2/TagAssignmentConfig":
+			fmt.Fprint(w, `{"value":{}}`)
+		case "/api/resources/workspace/v1/Workspace":
+			fmt.Fprint(w, `{"code":7,"message":"permission denied"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL + "/cvpservice", Client: srv.Client()}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := cvp.AssignTag(ctx, Tag{Label: "role", Value: "leaf"}, "SN1", "")
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected the workspace error to be reported, got %v", err)
+	}
+}
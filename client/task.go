@@ -0,0 +1,297 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Task states reported by CVP in Task.State
+const (
+	TaskActive    = "ACTIVE"
+	TaskCompleted = "COMPLETED"
+	TaskFailed    = "FAILED"
+	TaskCancelled = "CANCELLED"
+)
+
+// Task is a CVP task, created when a provisioning change is saved
+type Task struct {
+	ID                      string `json:"workOrderId"`
+	Description             string `json:"description"`
+	State                   string `json:"workOrderState"`
+	Status                  string `json:"workOrderUserDefinedStatus"`
+	NetElementID            string `json:"netElementId"`
+	CreatedBy               string `json:"createdBy"`
+	ExecutedBy              string `json:"executedBy"`
+	CreatedOnInLongFormat   int64  `json:"createdOnInLongFormat"`
+	CompletedOnInLongFormat int64  `json:"completedOnInLongFormat"`
+	Note                    string `json:"note"`
+	ChangeControlID         string `json:"ccId"`
+	Details                 struct {
+		Hostname     string `json:"netElementHostName"`
+		IPAddress    string `json:"ipAddress"`
+		SerialNumber string `json:"serialNumber"`
+	} `json:"workOrderDetails"`
+}
+
+// Created returns the time the task was created
+func (t Task) Created() time.Time {
+	return time.Unix(0, t.CreatedOnInLongFormat*int64(time.Millisecond))
+}
+
+// Completed returns the time the task finished, or the zero time if it has
+// not finished yet
+func (t Task) Completed() time.Time {
+	if t.CompletedOnInLongFormat == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t.CompletedOnInLongFormat*int64(time.Millisecond))
+}
+
+// TaskFilter selects the tasks returned by ListTasks, zero fields match any
+// task. State matches either the task state or its user defined status
+// (e.g. "Pending"), Device the device MAC address, hostname or IP address.
+type TaskFilter struct {
+	State  string
+	Device string
+	Since  time.Time
+}
+
+func (f TaskFilter) matches(t Task) bool {
+	if f.State != "" && !strings.EqualFold(f.State, t.State) && !strings.EqualFold(f.State, t.Status) {
+		return false
+	}
+	if f.Device != "" && f.Device != t.NetElementID && f.Device != t.Details.Hostname && f.Device != t.Details.IPAddress {
+		return false
+	}
+	if !f.Since.IsZero() && t.Created().Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+type taskListData struct {
+	Total        int    `json:"total"`
+	Data         []Task `json:"data"`
+	ErrorCode    string `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// GetTask returns the task with the given ID
+func (c *CvpClient) GetTask(ctx context.Context, taskID string) (Task, error) {
+	getTaskURL := "/task/getTaskById.do?taskId=" + url.QueryEscape(taskID)
+	task := Task{}
+	respbody, err := c.GetWithContext(ctx, getTaskURL)
+	if err != nil {
+		return task, err
+	}
+	resp := struct {
+		Task
+		ErrorCode    string `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding GetTask :%s\n", err)
+		return task, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return task, err
+	}
+	if resp.ID == "" {
+		return task, fmt.Errorf("No task with ID %s found", taskID)
+	}
+	return resp.Task, nil
+}
+
+// ListTasks returns all tasks matching filter
+func (c *CvpClient) ListTasks(ctx context.Context, filter TaskFilter) ([]Task, error) {
+	// CVP's queryparam is a free text search, so filtering is done here
+	getTasksURL := "/task/getTasks.do?queryparam=&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, getTasksURL)
+	if err != nil {
+		return nil, err
+	}
+	resp := taskListData{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListTasks :%s\n", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	for _, t := range resp.Data {
+		if filter.matches(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+// CancelTask cancels a task that has not been executed yet
+func (c *CvpClient) CancelTask(ctx context.Context, taskID string) error {
+	url := "/task/cancelTask.do"
+	data := JsonData{
+		Data: []string{taskID},
+	}
+	resp, err := c.CallWithContext(ctx, data, url)
+	if err != nil {
+		return err
+	}
+	responseBody := JsonData{}
+	if err = json.Unmarshal(resp, &responseBody); err != nil {
+		log.Printf("Error cancelling task %+v", err)
+		return err
+	}
+	return checkErrors(responseBody)
+}
+
+// AddTaskNote sets the note of a task
+func (c *CvpClient) AddTaskNote(ctx context.Context, taskID, note string) error {
+	url := "/task/addNoteToTask.do"
+	data := struct {
+		WorkOrderID string `json:"workOrderId"`
+		Note        string `json:"note"`
+	}{taskID, note}
+	resp, err := c.CallWithContext(ctx, data, url)
+	if err != nil {
+		return err
+	}
+	responseBody := JsonData{}
+	if err = json.Unmarshal(resp, &responseBody); err != nil {
+		log.Printf("Error adding note to task %+v", err)
+		return err
+	}
+	return checkErrors(responseBody)
+}
+
+// WaitOptions controls how WaitForTasks polls CVP. Zero values select
+// polling every second, without backoff, four tasks at a time.
+type WaitOptions struct {
+	// Interval is the delay between polls
+	Interval time.Duration
+	// Backoff multiplies the interval after every poll, up to MaxInterval
+	Backoff     float64
+	MaxInterval time.Duration
+	// Concurrency is the number of tasks fetched in parallel
+	Concurrency int
+	// Progress, if set, is called each time a task reaches a final state
+	Progress func(result TaskResult, done, total int)
+}
+
+// TaskResult is the last known state of a task waited on by WaitForTasks.
+// Err is set when the task failed, was cancelled or could not be fetched.
+type TaskResult struct {
+	Task  Task
+	State string
+	Err   error
+}
+
+func isTerminalState(state string) bool {
+	return state == TaskCompleted || state == TaskFailed || state == TaskCancelled
+}
+
+// WaitForTasks polls the given tasks until all of them have completed. It
+// returns as soon as a task fails, is cancelled or cannot be fetched, or
+// when ctx is done. The result map holds the last known state of every
+// task, whether the wait succeeded or not.
+func (c *CvpClient) WaitForTasks(ctx context.Context, taskIds []string, opts WaitOptions) (map[string]TaskResult, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 4
+	}
+	results := make(map[string]TaskResult, len(taskIds))
+	pending := make([]string, 0, len(taskIds))
+	for _, id := range taskIds {
+		if _, ok := results[id]; ok {
+			continue
+		}
+		results[id] = TaskResult{}
+		pending = append(pending, id)
+	}
+	done := 0
+	interval := opts.Interval
+	for {
+		polled := c.pollTasks(ctx, pending, opts.Concurrency)
+		var stillPending []string
+		var failure error
+		for i, id := range pending {
+			result := polled[i]
+			results[id] = result
+			if result.Err == nil && !isTerminalState(result.State) {
+				stillPending = append(stillPending, id)
+				continue
+			}
+			if result.Err != nil && ctx.Err() != nil {
+				// the wait was aborted, the task itself may still be fine
+				stillPending = append(stillPending, id)
+				continue
+			}
+			done++
+			if opts.Progress != nil {
+				opts.Progress(result, done, len(results))
+			}
+			if result.Err != nil && failure == nil {
+				failure = result.Err
+			}
+		}
+		pending = stillPending
+		if failure != nil {
+			return results, failure
+		}
+		if len(pending) == 0 {
+			return results, nil
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			for _, id := range pending {
+				result := results[id]
+				result.Err = ctx.Err()
+				results[id] = result
+			}
+			return results, ctx.Err()
+		}
+		if opts.Backoff > 1 {
+			interval = time.Duration(float64(interval) * opts.Backoff)
+			if opts.MaxInterval > 0 && interval > opts.MaxInterval {
+				interval = opts.MaxInterval
+			}
+		}
+	}
+}
+
+// pollTasks fetches the given tasks with up to concurrency requests in
+// flight and returns their results in the same order
+func (c *CvpClient) pollTasks(ctx context.Context, taskIds []string, concurrency int) []TaskResult {
+	results := make([]TaskResult, len(taskIds))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, id := range taskIds {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			task, err := c.GetTask(ctx, id)
+			if err != nil {
+				results[i] = TaskResult{Err: fmt.Errorf("Error fetching task %s : %s", id, err)}
+				return
+			}
+			result := TaskResult{Task: task, State: task.State}
+			if task.State == TaskFailed || task.State == TaskCancelled {
+				result.Err = fmt.Errorf("Task %s is %s%s", id, task.State, c.taskLogTail(ctx, id))
+			}
+			results[i] = result
+		}(i, id)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,169 @@
+package cvpgo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListTasks(t *testing.T) {
+	data := buildConfigletTestData()
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	filter := TaskFilter{
+		State:  TaskActive,
+		Device: data.NetElementID,
+		Since:  time.Now().Add(-24 * time.Hour),
+	}
+	tasks, err := cvp.ListTasks(context.Background(), filter)
+	if err != nil {
+		t.Errorf("Error listing tasks : %s", err)
+	}
+	for _, task := range tasks {
+		if task.State != TaskActive {
+			t.Errorf("Task %s is in state %s", task.ID, task.State)
+		}
+	}
+}
+
+func TestTaskNoteAndCancel(t *testing.T) {
+	data := buildConfigletTestData()
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	ctx := context.Background()
+	tasks, err := cvp.ListTasks(ctx, TaskFilter{State: TaskActive, Device: data.NetElementID})
+	if err != nil || len(tasks) == 0 {
+		t.Skipf("No pending tasks to work with : %v", err)
+	}
+	id := tasks[0].ID
+	if err = cvp.AddTaskNote(ctx, id, "cancelled by test"); err != nil {
+		t.Errorf("Error adding task note : %s", err)
+	}
+	if err = cvp.CancelTask(ctx, id); err != nil {
+		t.Errorf("Error cancelling task : %s", err)
+	}
+	task, err := cvp.GetTask(ctx, id)
+	if err != nil {
+		t.Errorf("Error getting task : %s", err)
+	}
+	if task.State != TaskCancelled {
+		t.Errorf("Task %s is in state %s after cancel", id, task.State)
+	}
+}
+
+func TestFollowTaskLogs(t *testing.T) {
+	data := buildConfigletTestData()
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	tasks, err := cvp.ListTasks(ctx, TaskFilter{Device: data.NetElementID})
+	if err != nil || len(tasks) == 0 {
+		t.Skipf("No tasks to work with : %v", err)
+	}
+	logs, err := cvp.GetTaskLogs(ctx, tasks[0].ID)
+	if err != nil {
+		t.Errorf("Error getting task logs : %s", err)
+	}
+	entries, errc := cvp.FollowTaskLogs(ctx, tasks[0].ID, time.Second)
+	followed := 0
+	for range entries {
+		followed++
+	}
+	if err = <-errc; err != nil {
+		t.Errorf("Error following task logs : %s", err)
+	}
+	if followed < len(logs) {
+		t.Errorf("Followed %d entries, expected at least %d", followed, len(logs))
+	}
+}
+
+func TestFollowTaskLogsRepeatedLines(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/task/getTaskById.do":
+			polls++
+			state := TaskActive
+			if polls > 1 {
+				state = TaskCompleted
+			}
+			fmt.Fprintf(w, `{"workOrderId":"1","workOrderState":"%s"}`, state)
+		case "/task/getLogsById.do":
+			logs := []string{
+				`{"activity":"a","description":"one","dateTimeInLongFormat":1}`,
+				`{"activity":"a","description":"two","dateTimeInLongFormat":1}`,
+			}
+			if polls > 1 {
+				logs = append(logs,
+					`{"activity":"a","description":"two","dateTimeInLongFormat":1}`,
+					`{"activity":"a","description":"three","dateTimeInLongFormat":2}`)
+			}
+			fmt.Fprintf(w, `{"total":%d,"data":[%s]}`, len(logs), strings.Join(logs, ","))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL, Client: srv.Client()}
+	entries, errc := cvp.FollowTaskLogs(context.Background(), "1", time.Millisecond)
+	var followed []string
+	for entry := range entries {
+		followed = append(followed, entry.Description)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("Error following task logs : %s", err)
+	}
+	if strings.Join(followed, ",") != "one,two,two,three" {
+		t.Errorf("Unexpected log lines %v", followed)
+	}
+}
+
+func TestWaitForTasks(t *testing.T) {
+	polls := map[string]int{}
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("taskId")
+		if r.URL.Path == "/task/getLogsById.do" {
+			fmt.Fprint(w, `{"total":1,"data":[{"activity":"Task","description":"config rejected"}]}`)
+			return
+		}
+		mu.Lock()
+		polls[id]++
+		n := polls[id]
+		mu.Unlock()
+		state := TaskActive
+		switch {
+		case id == "1" && n > 1:
+			state = TaskCompleted
+		case id == "2" && n > 2:
+			state = TaskFailed
+		}
+		fmt.Fprintf(w, `{"workOrderId":"%s","workOrderState":"%s"}`, id, state)
+	}))
+	defer srv.Close()
+	cvp := CvpClient{BaseURL: srv.URL, Client: srv.Client()}
+	var progress []string
+	opts := WaitOptions{
+		Interval: time.Millisecond,
+		Progress: func(r TaskResult, done, total int) { progress = append(progress, r.Task.ID) },
+	}
+	results, err := cvp.WaitForTasks(context.Background(), []string{"1", "2", "3"}, opts)
+	if err == nil || !strings.Contains(err.Error(), "config rejected") {
+		t.Errorf("Expected failure of task 2 with its logs, got %v", err)
+	}
+	if results["1"].State != TaskCompleted || results["2"].State != TaskFailed || results["3"].State != TaskActive {
+		t.Errorf("Unexpected results %+v", results)
+	}
+	if polls["1"] != 2 {
+		t.Errorf("Completed task was polled %d times", polls["1"])
+	}
+	if len(progress) != 2 {
+		t.Errorf("Expected progress for 2 tasks, got %+v", progress)
+	}
+}
@@ -0,0 +1,158 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultTaskLogTailLines is the number of log lines included in task errors
+const defaultTaskLogTailLines = 3
+
+// TaskLogEntry is one entry of a task's execution log
+type TaskLogEntry struct {
+	ObjectName           string `json:"objectName"`
+	Activity             string `json:"activity"`
+	Description          string `json:"description"`
+	UserName             string `json:"userName"`
+	DateTimeInLongFormat int64  `json:"dateTimeInLongFormat"`
+}
+
+// Time returns the time the entry was logged
+func (e TaskLogEntry) Time() time.Time {
+	return time.Unix(0, e.DateTimeInLongFormat*int64(time.Millisecond))
+}
+
+func (e TaskLogEntry) String() string {
+	return fmt.Sprintf("%s %s: %s", e.Time().Format(time.RFC3339), e.Activity, e.Description)
+}
+
+// GetTaskLogs returns the log entries of a task, oldest first
+func (c *CvpClient) GetTaskLogs(ctx context.Context, taskID string) ([]TaskLogEntry, error) {
+	getLogsURL := "/task/getLogsById.do?id=" + url.QueryEscape(taskID) + "&queryParam=&startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, getLogsURL)
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Total        int            `json:"total"`
+		Data         []TaskLogEntry `json:"data"`
+		ErrorCode    string         `json:"errorCode"`
+		ErrorMessage string         `json:"errorMessage"`
+	}{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding GetTaskLogs :%s\n", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(resp.Data, func(i, j int) bool {
+		return resp.Data[i].DateTimeInLongFormat < resp.Data[j].DateTimeInLongFormat
+	})
+	return resp.Data, nil
+}
+
+// taskLogLine identifies a log line by its timestamp and message
+type taskLogLine struct {
+	time        int64
+	activity    string
+	description string
+}
+
+// FollowTaskLogs polls the logs of a task every interval and sends entries
+// it has not seen before on the returned channel. Both channels are closed
+// once the task has finished or ctx is done, the error channel receives the
+// error that stopped the polling, if any.
+func (c *CvpClient) FollowTaskLogs(ctx context.Context, taskID string, interval time.Duration) (<-chan TaskLogEntry, <-chan error) {
+	entries := make(chan TaskLogEntry)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(entries)
+		defer close(errc)
+		// number of times each line was sent, a task can log the same
+		// message twice within a millisecond
+		sent := make(map[taskLogLine]int)
+		for {
+			// fetch the state before the logs so that the entries logged
+			// while the task finished are not missed
+			task, err := c.GetTask(ctx, taskID)
+			if err != nil {
+				errc <- err
+				return
+			}
+			logs, err := c.GetTaskLogs(ctx, taskID)
+			if err != nil {
+				errc <- err
+				return
+			}
+			polled := make(map[taskLogLine]int)
+			for _, entry := range logs {
+				line := taskLogLine{entry.DateTimeInLongFormat, entry.Activity, entry.Description}
+				polled[line]++
+				if polled[line] <= sent[line] {
+					continue
+				}
+				sent[line]++
+				select {
+				case entries <- entry:
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					return
+				}
+			}
+			if task.State != TaskActive {
+				return
+			}
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return entries, errc
+}
+
+// incompleteTaskLogs describes the tasks that have not completed together
+// with their last log lines, for use in error messages
+func (c *CvpClient) incompleteTaskLogs(ctx context.Context, taskIds []string) string {
+	var details []string
+	for _, id := range taskIds {
+		task, err := c.GetTask(ctx, id)
+		if err != nil {
+			details = append(details, fmt.Sprintf("task %s (%s)", id, err))
+			continue
+		}
+		if task.State == TaskCompleted {
+			continue
+		}
+		details = append(details, fmt.Sprintf("task %s (%s)%s", id, task.State, c.taskLogTail(ctx, id)))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(details, "; ")
+}
+
+// taskLogTail returns the last log lines of a task, for use in error messages
+func (c *CvpClient) taskLogTail(ctx context.Context, taskID string) string {
+	logs, err := c.GetTaskLogs(ctx, taskID)
+	if err != nil || len(logs) == 0 {
+		return ""
+	}
+	if len(logs) > defaultTaskLogTailLines {
+		logs = logs[len(logs)-defaultTaskLogTailLines:]
+	}
+	lines := make([]string, 0, len(logs))
+	for _, entry := range logs {
+		lines = append(lines, entry.String())
+	}
+	return " last log: " + strings.Join(lines, " | ")
+}
@@ -0,0 +1,173 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// TempActionList is the set of uncommitted actions in CVP's provisioning workspace
+type TempActionList struct {
+	Total        int      `json:"total"`
+	Data         []Action `json:"data"`
+	ErrorCode    string   `json:"errorCode"`
+	ErrorMessage string   `json:"errorMessage"`
+}
+
+// ListTempActions returns the temp actions that are waiting to be saved
+func (c *CvpClient) ListTempActions(ctx context.Context) ([]Action, error) {
+	url := "/provisioning/getAllTempActions.do?startIndex=0&endIndex=0"
+	respbody, err := c.GetWithContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	resp := TempActionList{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding ListTempActions :%s\n", err)
+		return nil, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: resp.ErrorCode, ErrorMessage: resp.ErrorMessage}); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// CancelTopology discards all temp actions that have not been saved yet.
+// CVP has no call to remove individual temp actions, so this includes
+// actions queued by earlier calls in the same session.
+func (c *CvpClient) CancelTopology(ctx context.Context) error {
+	url := "/provisioning/deleteAllTempSession.do"
+	respbody, err := c.GetWithContext(ctx, url)
+	if err != nil {
+		return err
+	}
+	resp := JsonData{}
+	if err = json.Unmarshal(respbody, &resp); err != nil {
+		log.Printf("Error decoding CancelTopology :%s\n", err)
+		return err
+	}
+	return checkErrors(resp)
+}
+
+// rollback is called by multi-step helpers when a step fails, so that the
+// temp actions added so far are not committed by the next save. It discards
+// the whole provisioning session with CancelTopology, not only the actions
+// the failed call added. It returns the original error, annotated if the
+// rollback failed too.
+func (c *CvpClient) rollback(ctx context.Context, cause error) error {
+	log.Printf("Discarding temp actions after error : %s", cause)
+	if err := c.CancelTopology(ctx); err != nil {
+		return fmt.Errorf("%s (discarding temp actions also failed: %s)", cause, err)
+	}
+	return cause
+}
+
+// AddTempActions adds actions to CVP's provisioning workspace so that they
+// are committed together by the next SaveTopology. If an action is rejected
+// every unsaved temp action is discarded, including those queued before
+// this call, see CancelTopology.
+func (c *CvpClient) AddTempActions(ctx context.Context, actions []Action) error {
+	for _, action := range actions {
+		if err := c.addTempAction(ctx, action); err != nil {
+			return c.rollback(ctx, err)
+		}
+	}
+	return nil
+}
+
+// SaveTopology commits the temp actions in CVP's provisioning workspace,
+// the returned data holds the IDs of the tasks created for them. The temp
+// actions are discarded if the save fails, see CancelTopology.
+func (c *CvpClient) SaveTopology(ctx context.Context) (SaveData, error) {
+	sdata, err := c.saveTopologyV2(ctx, []string{})
+	if err != nil {
+		return sdata, c.rollback(ctx, err)
+	}
+	return sdata, nil
+}
+
+// NewContainerAction returns the temp action creating a container below
+// parent
+func NewContainerAction(name string, parent Container) Action {
+	info := "Performing add operation on container " + name
+	return Action{
+		Info:        info,
+		InfoPreview: info,
+		Action:      "add",
+		NodeType:    "container",
+		NodeID:      "new_container",
+		NodeName:    name,
+		ToID:        parent.Key,
+		ToIDType:    "container",
+		ToName:      parent.Name,
+	}
+}
+
+// MoveDeviceAction returns the temp action moving a device from one
+// container to another
+func MoveDeviceAction(dev NetElement, from, to Container) Action {
+	return Action{
+		Info:        "Device Move: " + dev.Fqdn + " to " + to.Name,
+		InfoPreview: "<b>Device Move:</b> " + dev.Fqdn + " to " + to.Name,
+		Action:      "update",
+		NodeType:    "netelement",
+		NodeID:      dev.SystemMacAddress,
+		NodeName:    dev.Fqdn,
+		FromID:      from.Key,
+		FromName:    from.Name,
+		ToID:        to.Key,
+		ToIDType:    "container",
+		ToName:      to.Name,
+	}
+}
+
+// DeviceConfigletsAction returns the temp action setting the configlets
+// assigned to a device, configlets not in the list are removed from it
+func DeviceConfigletsAction(dev NetElement, configlets []Configlet) Action {
+	action := configletsAction(configlets)
+	action.Info = "Configlet Assign to device: " + dev.Fqdn
+	action.InfoPreview = "<b>Configlet assign</b> to Device " + dev.Fqdn
+	action.NodeIPAddress = dev.IPAddress
+	action.NodeTargetIPAddress = dev.IPAddress
+	action.ToID = dev.SystemMacAddress
+	action.ToIDType = "netelement"
+	action.ToName = dev.Fqdn
+	return action
+}
+
+// ContainerConfigletsAction returns the temp action setting the configlets
+// and configlet builders assigned to a container, those not in the list are
+// removed from it
+func ContainerConfigletsAction(container Container, configlets []Configlet) Action {
+	action := configletsAction(configlets)
+	action.Info = "Configlet Assign to container: " + container.Name
+	action.InfoPreview = "<b>Configlet assign</b> to Container " + container.Name
+	action.ToID = container.Key
+	action.ToIDType = "container"
+	action.ToName = container.Name
+	return action
+}
+
+func configletsAction(configlets []Configlet) Action {
+	var cfglets, builders []Configlet
+	for _, cfglet := range configlets {
+		if cfglet.Type == ConfigletTypeBuilder {
+			builders = append(builders, cfglet)
+		} else {
+			cfglets = append(cfglets, cfglet)
+		}
+	}
+	return Action{
+		Action:                          "associate",
+		NodeType:                        "configlet",
+		ConfigletList:                   getKeys(cfglets),
+		ConfigletNamesList:              getNames(cfglets),
+		ConfigletBuilderList:            getKeys(builders),
+		ConfigletBuilderNamesList:       getNames(builders),
+		IgnoreConfigletList:             []string{},
+		IgnoreConfigletNamesList:        []string{},
+		IgnoreConfigletBuilderList:      []string{},
+		IgnoreConfigletBuilderNamesList: []string{},
+	}
+}
@@ -0,0 +1,34 @@
+package cvpgo
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListTempActions(t *testing.T) {
+	testdata := buildTestData()
+	cvpInfo := *testdata.CVP
+	cvp := New(cvpInfo.IPAddress, cvpInfo.Username, cvpInfo.Password)
+	actions, err := cvp.ListTempActions(context.Background())
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+	t.Logf("Retrieved %d pending temp actions", len(actions))
+}
+
+func TestCancelTopology(t *testing.T) {
+	testdata := buildTestData()
+	cvpInfo := *testdata.CVP
+	cvp := New(cvpInfo.IPAddress, cvpInfo.Username, cvpInfo.Password)
+	ctx := context.Background()
+	if err := cvp.CancelTopology(ctx); err != nil {
+		t.Errorf("%+v", err)
+	}
+	actions, err := cvp.ListTempActions(ctx)
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+	if len(actions) != 0 {
+		t.Errorf("Expected no temp actions after cancel, got %d", len(actions))
+	}
+}
@@ -0,0 +1,291 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+)
+
+// User states accepted in User.UserStatus
+const (
+	UserEnabled  = "Enabled"
+	UserDisabled = "Disabled"
+)
+
+// Permission modules a role grants access to, as listed in the
+// permissionList cookie CVP hands out on login
+const (
+	ModuleAAA           = "aaa"
+	ModuleInventory     = "inventory"
+	ModuleBackupRestore = "backupRestore"
+	ModuleAccount       = "account"
+	ModuleImage         = "image"
+	ModuleLabel         = "label"
+	ModuleCvpTheme      = "cvpTheme"
+	ModuleSnapshot      = "snapshot"
+	ModuleConfiglet     = "configlet"
+	ModuleDanz          = "danz"
+	ModuleTask          = "task"
+	ModuleChangeControl = "changeControl"
+	ModuleZtp           = "ztp"
+)
+
+// Permission modes of a role module
+const (
+	ModeReadOnly  = "r"
+	ModeReadWrite = "rw"
+)
+
+// Role is a named set of module permissions assigned to users
+type Role struct {
+	Key         string       `json:"key,omitempty"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	ModuleList  []RoleModule `json:"moduleList"`
+}
+
+// RoleModule grants access to one permission module
+type RoleModule struct {
+	Name string `json:"name"`
+	Mode string `json:"mode"`
+}
+
+// AAASettings selects the servers CVP authenticates and authorises
+// users against, "local" or one of "RADIUS" and "TACACS"
+type AAASettings struct {
+	AuthenticationServerType string `json:"authenticationServerType"`
+	AuthorizationServerType  string `json:"authorizationServerType"`
+}
+
+// AAAServer is a RADIUS or TACACS server used by CVP
+type AAAServer struct {
+	ID                      string `json:"id,omitempty"`
+	ServerType              string `json:"serverType"`
+	IPAddress               string `json:"ipAddress"`
+	Port                    int    `json:"port"`
+	AuthMode                string `json:"authMode"`
+	SecretKey               string `json:"secret,omitempty"`
+	Status                  string `json:"status"`
+	AccountPort             int    `json:"accountPort,omitempty"`
+	CreatedDateInLongFormat int64  `json:"createdDateInLongFormat,omitempty"`
+}
+
+type userRequest struct {
+	User  User     `json:"user"`
+	Roles []string `json:"roles"`
+}
+
+// decodeCvpResponse decodes a CVP response into v and checks it for errors
+func decodeCvpResponse(resp []byte, v interface{}, op string) error {
+	if err := json.Unmarshal(resp, v); err != nil {
+		log.Printf("Error decoding %s :%s\n", op, err)
+		return err
+	}
+	errors := JsonData{}
+	if err := json.Unmarshal(resp, &errors); err != nil {
+		return nil
+	}
+	return checkErrors(errors)
+}
+
+// ListUsers returns all CVP users together with the names of their roles
+func (c *CvpClient) ListUsers(ctx context.Context) ([]User, map[string][]string, error) {
+	respbody, err := c.GetWithContext(ctx, "/user/getUsers.do?queryparam=&startIndex=0&endIndex=0")
+	if err != nil {
+		return nil, nil, err
+	}
+	resp := struct {
+		Total int                 `json:"total"`
+		Users []User              `json:"users"`
+		Roles map[string][]string `json:"roles"`
+	}{}
+	if err = decodeCvpResponse(respbody, &resp, "ListUsers"); err != nil {
+		return nil, nil, err
+	}
+	return resp.Users, resp.Roles, nil
+}
+
+// GetUser returns a CVP user together with the names of its roles
+func (c *CvpClient) GetUser(ctx context.Context, userID string) (User, []string, error) {
+	respbody, err := c.GetWithContext(ctx, "/user/getUser.do?userId="+url.QueryEscape(userID))
+	if err != nil {
+		return User{}, nil, err
+	}
+	resp := struct {
+		User  User     `json:"user"`
+		Roles []string `json:"roles"`
+	}{}
+	if err = decodeCvpResponse(respbody, &resp, "GetUser"); err != nil {
+		return User{}, nil, err
+	}
+	if resp.User.UserID == "" {
+		return User{}, nil, fmt.Errorf("No user named \"%s\" found", userID)
+	}
+	return resp.User, resp.Roles, nil
+}
+
+// CreateUser creates a local CVP user with the given roles
+func (c *CvpClient) CreateUser(ctx context.Context, user User, roles []string) error {
+	if user.UserStatus == "" {
+		user.UserStatus = UserEnabled
+	}
+	if user.UserType == "" {
+		user.UserType = "Local"
+	}
+	resp, err := c.CallWithContext(ctx, userRequest{user, roles}, "/user/addUser.do")
+	if err != nil {
+		return err
+	}
+	return decodeCvpResponse(resp, &JsonData{}, "CreateUser")
+}
+
+// UpdateUser replaces the details and roles of a user, an empty password
+// keeps the current one
+func (c *CvpClient) UpdateUser(ctx context.Context, user User, roles []string) error {
+	resp, err := c.CallWithContext(ctx, userRequest{user, roles}, "/user/updateUser.do?userId="+url.QueryEscape(user.UserID))
+	if err != nil {
+		return err
+	}
+	return decodeCvpResponse(resp, &JsonData{}, "UpdateUser")
+}
+
+// DeleteUser deletes a CVP user
+func (c *CvpClient) DeleteUser(ctx context.Context, userID string) error {
+	resp, err := c.CallWithContext(ctx, []string{userID}, "/user/deleteUsers.do")
+	if err != nil {
+		return err
+	}
+	return decodeCvpResponse(resp, &JsonData{}, "DeleteUser")
+}
+
+// EnableUser allows a user to log in again
+func (c *CvpClient) EnableUser(ctx context.Context, userID string) error {
+	return c.setUserStatus(ctx, userID, UserEnabled)
+}
+
+// DisableUser prevents a user from logging in without deleting it
+func (c *CvpClient) DisableUser(ctx context.Context, userID string) error {
+	return c.setUserStatus(ctx, userID, UserDisabled)
+}
+
+func (c *CvpClient) setUserStatus(ctx context.Context, userID, status string) error {
+	user, roles, err := c.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.UserStatus = status
+	return c.UpdateUser(ctx, user, roles)
+}
+
+// ListRoles returns all roles
+func (c *CvpClient) ListRoles(ctx context.Context) ([]Role, error) {
+	respbody, err := c.GetWithContext(ctx, "/role/getRoles.do?queryParam=&startIndex=0&endIndex=0")
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Total int    `json:"total"`
+		Roles []Role `json:"roles"`
+	}{}
+	if err = decodeCvpResponse(respbody, &resp, "ListRoles"); err != nil {
+		return nil, err
+	}
+	return resp.Roles, nil
+}
+
+// CreateRole creates a role and returns its key
+func (c *CvpClient) CreateRole(ctx context.Context, role Role) (string, error) {
+	resp, err := c.CallWithContext(ctx, role, "/role/createRole.do")
+	if err != nil {
+		return "", err
+	}
+	created := Role{}
+	if err = decodeCvpResponse(resp, &created, "CreateRole"); err != nil {
+		return "", err
+	}
+	return created.Key, nil
+}
+
+// UpdateRole replaces the description and modules of the role with role.Key
+func (c *CvpClient) UpdateRole(ctx context.Context, role Role) error {
+	resp, err := c.CallWithContext(ctx, role, "/role/updateRole.do")
+	if err != nil {
+		return err
+	}
+	return decodeCvpResponse(resp, &JsonData{}, "UpdateRole")
+}
+
+// DeleteRole deletes the role with the given key
+func (c *CvpClient) DeleteRole(ctx context.Context, key string) error {
+	resp, err := c.CallWithContext(ctx, []string{key}, "/role/deleteRoles.do")
+	if err != nil {
+		return err
+	}
+	return decodeCvpResponse(resp, &JsonData{}, "DeleteRole")
+}
+
+// GetAAASettings returns the authentication and authorisation settings
+func (c *CvpClient) GetAAASettings(ctx context.Context) (AAASettings, error) {
+	settings := AAASettings{}
+	respbody, err := c.GetWithContext(ctx, "/aaa/getAAADetails.do")
+	if err != nil {
+		return settings, err
+	}
+	err = decodeCvpResponse(respbody, &settings, "GetAAASettings")
+	return settings, err
+}
+
+// SetAAASettings changes the authentication and authorisation settings
+func (c *CvpClient) SetAAASettings(ctx context.Context, settings AAASettings) error {
+	resp, err := c.CallWithContext(ctx, settings, "/aaa/saveAAADetails.do")
+	if err != nil {
+		return err
+	}
+	return decodeCvpResponse(resp, &JsonData{}, "SetAAASettings")
+}
+
+// ListAAAServers returns the configured servers of the given type, RADIUS
+// or TACACS
+func (c *CvpClient) ListAAAServers(ctx context.Context, serverType string) ([]AAAServer, error) {
+	respbody, err := c.GetWithContext(ctx, "/aaa/getServers.do?serverType="+url.QueryEscape(serverType)+"&queryParam=&startIndex=0&endIndex=0")
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Total int         `json:"total"`
+		Data  []AAAServer `json:"aaaServers"`
+	}{}
+	if err = decodeCvpResponse(respbody, &resp, "ListAAAServers"); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// AddAAAServer adds a RADIUS or TACACS server
+func (c *CvpClient) AddAAAServer(ctx context.Context, server AAAServer) error {
+	resp, err := c.CallWithContext(ctx, server, "/aaa/createServer.do")
+	if err != nil {
+		return err
+	}
+	return decodeCvpResponse(resp, &JsonData{}, "AddAAAServer")
+}
+
+// UpdateAAAServer replaces the settings of the server with server.ID
+func (c *CvpClient) UpdateAAAServer(ctx context.Context, server AAAServer) error {
+	resp, err := c.CallWithContext(ctx, server, "/aaa/editServer.do")
+	if err != nil {
+		return err
+	}
+	return decodeCvpResponse(resp, &JsonData{}, "UpdateAAAServer")
+}
+
+// DeleteAAAServer deletes the server with the given ID
+func (c *CvpClient) DeleteAAAServer(ctx context.Context, id string) error {
+	resp, err := c.CallWithContext(ctx, []string{id}, "/aaa/deleteServer.do")
+	if err != nil {
+		return err
+	}
+	return decodeCvpResponse(resp, &JsonData{}, "DeleteAAAServer")
+}
@@ -0,0 +1,54 @@
+package cvpgo
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUserLifecycle(t *testing.T) {
+	data := buildConfigletTestData()
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	ctx := context.Background()
+	role := Role{
+		Name:        "cvpgo-test-role",
+		Description: "cvpgo test role",
+		ModuleList: []RoleModule{
+			{Name: ModuleConfiglet, Mode: ModeReadWrite},
+			{Name: ModuleInventory, Mode: ModeReadOnly},
+		},
+	}
+	key, err := cvp.CreateRole(ctx, role)
+	if err != nil {
+		t.Fatalf("Error creating role : %s", err)
+	}
+	defer cvp.DeleteRole(ctx, key)
+	user := User{UserID: "cvpgo-test", Password: "cvpgo-test", Email: "cvpgo-test@example.com"}
+	if err = cvp.CreateUser(ctx, user, []string{role.Name}); err != nil {
+		t.Fatalf("Error creating user : %s", err)
+	}
+	if err = cvp.DisableUser(ctx, user.UserID); err != nil {
+		t.Errorf("Error disabling user : %s", err)
+	}
+	got, roles, err := cvp.GetUser(ctx, user.UserID)
+	if err != nil {
+		t.Errorf("Error getting user : %s", err)
+	}
+	if got.UserStatus != UserDisabled || len(roles) != 1 {
+		t.Errorf("Unexpected user %+v with roles %v", got, roles)
+	}
+	if err = cvp.DeleteUser(ctx, user.UserID); err != nil {
+		t.Errorf("Error deleting user : %s", err)
+	}
+}
+
+func TestGetAAASettings(t *testing.T) {
+	data := buildConfigletTestData()
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	settings, err := cvp.GetAAASettings(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting AAA settings : %s", err)
+	}
+	if settings.AuthenticationServerType == "" {
+		t.Errorf("Unexpected AAA settings %+v", settings)
+	}
+}
@@ -0,0 +1,157 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ValidationMessage is an error or warning CVP reported for a config line.
+// Line is 0 when CVP did not tie the message to a line.
+type ValidationMessage struct {
+	Line int
+	Text string
+}
+
+// UnmarshalJSON accepts both plain string messages and CVP's
+// {"lineNo": " 3", "error": "..."} objects
+func (m *ValidationMessage) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		m.Text = text
+		return nil
+	}
+	raw := struct {
+		LineNo  json.RawMessage `json:"lineNo"`
+		Error   string          `json:"error"`
+		Warning string          `json:"warning"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.Text = raw.Error
+	if m.Text == "" {
+		m.Text = raw.Warning
+	}
+	lineNo := strings.Trim(string(raw.LineNo), "\" ")
+	if lineNo != "" && lineNo != "null" {
+		line, err := strconv.Atoi(lineNo)
+		if err != nil {
+			return fmt.Errorf("Invalid line number %s in validation message", raw.LineNo)
+		}
+		m.Line = line
+	}
+	return nil
+}
+
+func (m ValidationMessage) String() string {
+	if m.Line == 0 {
+		return m.Text
+	}
+	return fmt.Sprintf("line %d: %s", m.Line, m.Text)
+}
+
+// ValidationResult holds the errors and warnings from validating a config
+// against a device
+type ValidationResult struct {
+	NetElementID string
+	Errors       []ValidationMessage
+	Warnings     []ValidationMessage
+}
+
+// Failed reports whether the config has errors, or with strict set,
+// warnings
+func (r ValidationResult) Failed(strict bool) bool {
+	return len(r.Errors) > 0 || (strict && len(r.Warnings) > 0)
+}
+
+// Err returns an error listing the validation messages if the result Failed
+func (r ValidationResult) Err(strict bool) error {
+	if !r.Failed(strict) {
+		return nil
+	}
+	messages := make([]string, 0, len(r.Errors)+len(r.Warnings))
+	for _, m := range r.Errors {
+		messages = append(messages, "error "+m.String())
+	}
+	if strict {
+		for _, m := range r.Warnings {
+			messages = append(messages, "warning "+m.String())
+		}
+	}
+	return fmt.Errorf("Config validation produced errors: %s", strings.Join(messages, "; "))
+}
+
+// ValidateConfigDetails validates config against the device identified by
+// netElementID and returns every error and warning CVP reported
+func (c *CvpClient) ValidateConfigDetails(ctx context.Context, netElementID, config string) (ValidationResult, error) {
+	url := "/configlet/validateConfig.do"
+	req := ValidateConfigRequest{
+		NetElementID: netElementID,
+		Config:       config,
+	}
+	result := ValidationResult{NetElementID: netElementID}
+	resp, err := c.CallWithContext(ctx, req, url)
+	if err != nil {
+		return result, err
+	}
+	body := ValidateConfigResponse{}
+	if err = json.Unmarshal(resp, &body); err != nil {
+		log.Printf("Error validating config %+v", err)
+		return result, err
+	}
+	if err = checkErrors(JsonData{ErrorCode: body.ErrorCode, ErrorMessage: body.ErrorMessage}); err != nil {
+		return result, err
+	}
+	result.Errors = body.Errors
+	result.Warnings = body.Warnings
+	// older CVP releases only return the counts
+	if len(result.Errors) == 0 && body.ErrorCount > 0 {
+		result.Errors = []ValidationMessage{{Text: fmt.Sprintf("%d errors", body.ErrorCount)}}
+	}
+	if len(result.Warnings) == 0 && body.WarningCount > 0 {
+		result.Warnings = []ValidationMessage{{Text: fmt.Sprintf("%d warnings", body.WarningCount)}}
+	}
+	return result, nil
+}
+
+// DeviceValidation is the outcome of validating a config against one device
+// as part of ValidateConfigBatch. Err is set when the device could not be
+// validated at all.
+type DeviceValidation struct {
+	Result ValidationResult
+	Err    error
+}
+
+// ValidateConfigBatch validates config against every device in
+// netElementIDs, running up to concurrency validations at a time.
+// Results are returned in the order of netElementIDs.
+func (c *CvpClient) ValidateConfigBatch(ctx context.Context, config string, netElementIDs []string, concurrency int) []DeviceValidation {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]DeviceValidation, len(netElementIDs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, id := range netElementIDs {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i] = DeviceValidation{Result: ValidationResult{NetElementID: id}, Err: ctx.Err()}
+				return
+			}
+			defer func() { <-sem }()
+			result, err := c.ValidateConfigDetails(ctx, id, config)
+			results[i] = DeviceValidation{Result: result, Err: err}
+		}(i, id)
+	}
+	wg.Wait()
+	return results
+}
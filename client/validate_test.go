@@ -0,0 +1,40 @@
+package cvpgo
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestValidationMessageDecode(t *testing.T) {
+	raw := `{"warnings":["deprecated command"],"errors":[{"lineNo":" 2","error":"> bogus% Invalid input"}],"warningCount":1,"errorCount":1}`
+	body := ValidateConfigResponse{}
+	if err := json.Unmarshal([]byte(raw), &body); err != nil {
+		t.Fatalf("Error decoding validation response : %s", err)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Line != 2 || body.Errors[0].Text != "> bogus% Invalid input" {
+		t.Errorf("Unexpected errors %+v", body.Errors)
+	}
+	result := ValidationResult{Errors: nil, Warnings: body.Warnings}
+	if result.Err(false) != nil {
+		t.Errorf("Warnings must not fail validation unless strict")
+	}
+	if result.Err(true) == nil {
+		t.Errorf("Warnings must fail strict validation")
+	}
+}
+
+func TestValidateConfigBatch(t *testing.T) {
+	data := buildConfigletTestData()
+	t.Logf("Test data: %+v", data)
+	cvp := New(data.CvpIP, data.CvpUser, data.CvpPwd)
+	results := cvp.ValidateConfigBatch(context.Background(), data.NewConfig, []string{data.NetElementID}, 4)
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("Error validating config on %s : %s", r.Result.NetElementID, r.Err)
+		}
+		if err := r.Result.Err(false); err != nil {
+			t.Errorf("%s", err)
+		}
+	}
+}
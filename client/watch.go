@@ -0,0 +1,261 @@
+package cvpgo
+
+import (
+	"context"
+	"time"
+)
+
+// WatchKind selects the objects Watch reports changes for
+type WatchKind string
+
+// Kinds of objects that can be watched
+const (
+	WatchDevices    WatchKind = "devices"
+	WatchTasks      WatchKind = "tasks"
+	WatchConfiglets WatchKind = "configlets"
+)
+
+// ChangeType describes what happened to a watched object
+type ChangeType string
+
+// Changes reported by Watch
+const (
+	DeviceAdded         ChangeType = "DeviceAdded"
+	DeviceRemoved       ChangeType = "DeviceRemoved"
+	DeviceStatusChanged ChangeType = "DeviceStatusChanged"
+	TaskAdded           ChangeType = "TaskAdded"
+	TaskStateChanged    ChangeType = "TaskStateChanged"
+	ConfigletAdded      ChangeType = "ConfigletAdded"
+	ConfigletRemoved    ChangeType = "ConfigletRemoved"
+	ConfigletModified   ChangeType = "ConfigletModified"
+	// PollFailed reports that listing the watched objects failed, Err
+	// holds the error. Polling carries on after a backoff.
+	PollFailed ChangeType = "PollFailed"
+)
+
+// defaultWatchInterval is the polling interval used by Watch
+const defaultWatchInterval = 5 * time.Second
+
+// defaultWatchBackoff is the longest wait between failed polls
+const defaultWatchBackoff = time.Minute
+
+// Change is a change to a watched object. Only the field matching the kind
+// of object is set, holding the object as last seen. OldState and NewState
+// hold the device status or task state before and after a state change.
+type Change struct {
+	Type      ChangeType
+	Time      time.Time
+	Device    *NetElement
+	Task      *Task
+	Configlet *Configlet
+	OldState  string
+	NewState  string
+	Err       error
+}
+
+// WatchSource produces the changes reported by Watch. Run sends changes to
+// the given kinds of objects on changes until ctx is done or it fails, it
+// must not close changes.
+type WatchSource interface {
+	Run(ctx context.Context, kinds []WatchKind, changes chan<- Change) error
+}
+
+// Watch reports changes to the given kinds of objects, or to all of them
+// when no kind is given, by polling CVP every few seconds. Failed polls are
+// reported as PollFailed changes and retried with a backoff. Both channels
+// are closed once ctx is done, the error channel receives the error that
+// stopped the watch.
+func (c *CvpClient) Watch(ctx context.Context, kinds ...WatchKind) (<-chan Change, <-chan error) {
+	return WatchWith(ctx, &PollingSource{Client: c, Interval: defaultWatchInterval}, kinds...)
+}
+
+// WatchWith is Watch with changes produced by src
+func WatchWith(ctx context.Context, src WatchSource, kinds ...WatchKind) (<-chan Change, <-chan error) {
+	if len(kinds) == 0 {
+		kinds = []WatchKind{WatchDevices, WatchTasks, WatchConfiglets}
+	}
+	changes := make(chan Change)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(changes)
+		defer close(errc)
+		if err := src.Run(ctx, kinds, changes); err != nil {
+			errc <- err
+		}
+	}()
+	return changes, errc
+}
+
+// PollingSource is a WatchSource that lists the watched objects every
+// Interval and reports the differences to the previous listing. The first
+// listing only records the current state. CVP has no way to list only what
+// changed, so every poll fetches all watched objects. When a poll fails the
+// wait before the next one doubles, up to MaxBackoff or a minute when it is
+// not set. An Interval of 0 polls every 5 seconds, as Watch does.
+type PollingSource struct {
+	Client     *CvpClient
+	Interval   time.Duration
+	MaxBackoff time.Duration
+}
+
+// watchState is the last seen state of the watched objects, keyed by ID
+type watchState struct {
+	devices    map[string]NetElement
+	tasks      map[string]Task
+	configlets map[string]Configlet
+}
+
+// Run implements WatchSource
+func (p *PollingSource) Run(ctx context.Context, kinds []WatchKind, changes chan<- Change) error {
+	var prev *watchState
+	failures := 0
+	for {
+		wait := p.interval()
+		cur, err := p.poll(ctx, kinds)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failures++
+			wait = p.backoff(failures)
+			select {
+			case changes <- Change{Type: PollFailed, Time: time.Now(), Err: err}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else {
+			failures = 0
+			if prev != nil {
+				for _, change := range prev.diff(cur, time.Now()) {
+					select {
+					case changes <- change:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			}
+			prev = cur
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// interval returns the wait between successful polls
+func (p *PollingSource) interval() time.Duration {
+	if p.Interval <= 0 {
+		return defaultWatchInterval
+	}
+	return p.Interval
+}
+
+// backoff returns the wait after the given number of consecutive failed
+// polls
+func (p *PollingSource) backoff(failures int) time.Duration {
+	max := p.MaxBackoff
+	if max == 0 {
+		max = defaultWatchBackoff
+	}
+	wait := p.interval()
+	for i := 0; i < failures && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max || wait <= 0 {
+		wait = max
+	}
+	return wait
+}
+
+func (p *PollingSource) poll(ctx context.Context, kinds []WatchKind) (*watchState, error) {
+	state := &watchState{}
+	for _, kind := range kinds {
+		switch kind {
+		case WatchDevices:
+			devices, err := p.Client.inventory(ctx, "")
+			if err != nil {
+				return nil, err
+			}
+			state.devices = make(map[string]NetElement, len(devices))
+			for _, d := range devices {
+				state.devices[d.SystemMacAddress] = d
+			}
+		case WatchTasks:
+			tasks, err := p.Client.ListTasks(ctx, TaskFilter{})
+			if err != nil {
+				return nil, err
+			}
+			state.tasks = make(map[string]Task, len(tasks))
+			for _, t := range tasks {
+				state.tasks[t.ID] = t
+			}
+		case WatchConfiglets:
+			configlets, _, err := p.Client.ListConfiglets(ctx, ConfigletFilter{})
+			if err != nil {
+				return nil, err
+			}
+			state.configlets = make(map[string]Configlet, len(configlets))
+			for _, cfg := range configlets {
+				state.configlets[cfg.Key] = cfg
+			}
+		}
+	}
+	return state, nil
+}
+
+// diff returns the changes from s to cur, a kind that is not watched has a
+// nil map in both states and yields no changes
+func (s *watchState) diff(cur *watchState, now time.Time) []Change {
+	var changes []Change
+	for id, d := range cur.devices {
+		d := d
+		old, ok := s.devices[id]
+		if !ok {
+			changes = append(changes, Change{Type: DeviceAdded, Time: now, Device: &d, NewState: deviceState(d)})
+		} else if old.Status != d.Status || old.StreamingStatus != d.StreamingStatus {
+			changes = append(changes, Change{Type: DeviceStatusChanged, Time: now, Device: &d, OldState: deviceState(old), NewState: deviceState(d)})
+		}
+	}
+	for id, d := range s.devices {
+		d := d
+		if _, ok := cur.devices[id]; !ok {
+			changes = append(changes, Change{Type: DeviceRemoved, Time: now, Device: &d, OldState: deviceState(d)})
+		}
+	}
+	for id, t := range cur.tasks {
+		t := t
+		old, ok := s.tasks[id]
+		if !ok {
+			changes = append(changes, Change{Type: TaskAdded, Time: now, Task: &t, NewState: t.State})
+		} else if old.State != t.State || old.Status != t.Status {
+			changes = append(changes, Change{Type: TaskStateChanged, Time: now, Task: &t, OldState: old.State, NewState: t.State})
+		}
+	}
+	for key, cfg := range cur.configlets {
+		cfg := cfg
+		old, ok := s.configlets[key]
+		if !ok {
+			changes = append(changes, Change{Type: ConfigletAdded, Time: now, Configlet: &cfg})
+		} else if old.Name != cfg.Name || old.Config != cfg.Config || old.DateTimeInLongFormat != cfg.DateTimeInLongFormat {
+			changes = append(changes, Change{Type: ConfigletModified, Time: now, Configlet: &cfg})
+		}
+	}
+	for key, cfg := range s.configlets {
+		cfg := cfg
+		if _, ok := cur.configlets[key]; !ok {
+			changes = append(changes, Change{Type: ConfigletRemoved, Time: now, Configlet: &cfg})
+		}
+	}
+	return changes
+}
+
+// deviceState combines the device and streaming status, either can change
+// on its own
+func deviceState(d NetElement) string {
+	if d.StreamingStatus == "" {
+		return d.Status
+	}
+	return d.Status + "/" + d.StreamingStatus
+}
@@ -0,0 +1,146 @@
+package cvpgo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchStateDiff(t *testing.T) {
+	prev := &watchState{
+		devices: map[string]NetElement{
+			"00:1c:73:00:00:01": {SystemMacAddress: "00:1c:73:00:00:01", Status: "Registered"},
+			"00:1c:73:00:00:02": {SystemMacAddress: "00:1c:73:00:00:02", Status: "Registered"},
+		},
+		tasks: map[string]Task{
+			"1": {ID: "1", State: TaskActive},
+		},
+		configlets: map[string]Configlet{
+			"configlet_1": {Key: "configlet_1", Name: "Test1", Config: "hostname a"},
+		},
+	}
+	cur := &watchState{
+		devices: map[string]NetElement{
+			"00:1c:73:00:00:01": {SystemMacAddress: "00:1c:73:00:00:01", Status: "Disconnected"},
+			"00:1c:73:00:00:03": {SystemMacAddress: "00:1c:73:00:00:03", Status: "Registered"},
+		},
+		tasks: map[string]Task{
+			"1": {ID: "1", State: TaskCompleted},
+			"2": {ID: "2", State: TaskActive},
+		},
+		configlets: map[string]Configlet{
+			"configlet_1": {Key: "configlet_1", Name: "Test1", Config: "hostname b"},
+		},
+	}
+	changes := make(map[ChangeType]Change)
+	for _, change := range prev.diff(cur, time.Now()) {
+		changes[change.Type] = change
+	}
+	if len(changes) != 6 {
+		t.Errorf("Expected 6 kinds of changes, got %+v", changes)
+	}
+	if c := changes[DeviceStatusChanged]; c.OldState != "Registered" || c.NewState != "Disconnected" {
+		t.Errorf("Unexpected device status change %+v", c)
+	}
+	if c := changes[DeviceAdded]; c.Device == nil || c.Device.SystemMacAddress != "00:1c:73:00:00:03" {
+		t.Errorf("Unexpected device added %+v", c)
+	}
+	if c := changes[DeviceRemoved]; c.Device == nil || c.Device.SystemMacAddress != "00:1c:73:00:00:02" {
+		t.Errorf("Unexpected device removed %+v", c)
+	}
+	if c := changes[TaskStateChanged]; c.Task == nil || c.Task.ID != "1" || c.NewState != TaskCompleted {
+		t.Errorf("Unexpected task state change %+v", c)
+	}
+	if c := changes[ConfigletModified]; c.Configlet == nil || c.Configlet.Config != "hostname b" {
+		t.Errorf("Unexpected configlet change %+v", c)
+	}
+	if len(prev.diff(prev, time.Now())) != 0 {
+		t.Errorf("Expected no changes between identical states")
+	}
+}
+
+type fakeSource []Change
+
+func (f fakeSource) Run(ctx context.Context, kinds []WatchKind, changes chan<- Change) error {
+	for _, change := range f {
+		select {
+		case changes <- change:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWatchWith(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, errc := WatchWith(ctx, fakeSource{{Type: TaskAdded}, {Type: TaskStateChanged}})
+	for _, want := range []ChangeType{TaskAdded, TaskStateChanged} {
+		if got := <-changes; got.Type != want {
+			t.Errorf("Got change %s, want %s", got.Type, want)
+		}
+	}
+	cancel()
+	if _, ok := <-changes; ok {
+		t.Errorf("Expected changes to be closed")
+	}
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestPollingSourceRetries(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls++
+		switch {
+		case polls == 1:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case polls == 2:
+			fmt.Fprint(w, `{"netElementList":[{"systemMacAddress":"a"}]}`)
+		default:
+			fmt.Fprint(w, `{"netElementList":[{"systemMacAddress":"a"},{"systemMacAddress":"b"}]}`)
+		}
+	}))
+	defer srv.Close()
+	cvp := &CvpClient{BaseURL: srv.URL, Client: srv.Client()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	src := &PollingSource{Client: cvp, Interval: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	changes, _ := WatchWith(ctx, src, WatchDevices)
+	if c := <-changes; c.Type != PollFailed || c.Err == nil {
+		t.Errorf("Expected a failed poll, got %+v", c)
+	}
+	if c := <-changes; c.Type != DeviceAdded || c.Device.SystemMacAddress != "b" {
+		t.Errorf("Expected device b to be added, got %+v", c)
+	}
+}
+
+func TestPollingSourceBackoff(t *testing.T) {
+	src := &PollingSource{Interval: time.Second, MaxBackoff: 5 * time.Second}
+	for failures, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := src.backoff(failures); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestPollingSourceDefaultInterval(t *testing.T) {
+	src := &PollingSource{}
+	for failures, want := range []time.Duration{defaultWatchInterval, 2 * defaultWatchInterval, 4 * defaultWatchInterval} {
+		if got := src.backoff(failures); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", failures, got, want)
+		}
+	}
+	if got := src.backoff(10); got != defaultWatchBackoff {
+		t.Errorf("backoff(10) = %s, want %s", got, defaultWatchBackoff)
+	}
+}
@@ -0,0 +1,183 @@
+package desired
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	cvpgo "github.com/fredhsu/cvpgo/client"
+)
+
+// deviceConnectTimeout is how long, in seconds, Apply waits for a device
+// added to the inventory to connect
+const deviceConnectTimeout = 120
+
+// Apply makes the changes of the plan and returns the IDs of the tasks they
+// created. Configlets are created and updated first, then containers are
+// created one tree level at a time and missing devices are added to the
+// inventory. Device moves and configlet assignments are then added as
+// temp actions and saved together, so that either all of them or none are
+// committed. The tasks are not executed.
+func (p *Plan) Apply(ctx context.Context, c *cvpgo.CvpClient) ([]string, error) {
+	var taskIds []string
+	planned := make(map[stepKey]bool)
+	for _, s := range p.Steps {
+		planned[stepKey{s.Op, s.Kind, s.Name}] = true
+	}
+
+	for _, want := range p.state.Configlets {
+		if !planned[stepKey{OpCreate, KindConfiglet, want.Name}] && !planned[stepKey{OpUpdate, KindConfiglet, want.Name}] {
+			continue
+		}
+		_, ids, err := c.UpsertConfiglet(ctx, cvpgo.Configlet{Name: want.Name, Config: want.Config})
+		if err != nil {
+			return taskIds, err
+		}
+		taskIds = append(taskIds, ids...)
+	}
+
+	levels, err := p.state.containerLevels()
+	if err != nil {
+		return taskIds, err
+	}
+	for _, level := range levels {
+		var created []Container
+		for _, want := range level {
+			if planned[stepKey{OpCreate, KindContainer, want.Name}] {
+				created = append(created, want)
+			}
+		}
+		if err = p.createContainers(ctx, c, created); err != nil {
+			return taskIds, err
+		}
+	}
+
+	for _, want := range p.state.Devices {
+		if !planned[stepKey{OpCreate, KindDevice, want.Hostname}] {
+			continue
+		}
+		log.Printf("Adding device %s (%s) to container %s", want.Hostname, want.IPAddress, want.Container)
+		if err = c.AddDevice(want.IPAddress, want.Container); err != nil {
+			return taskIds, err
+		}
+		if err = c.SaveCommit(want.IPAddress, deviceConnectTimeout); err != nil {
+			return taskIds, fmt.Errorf("Device \"%s\" was not added : %s", want.Hostname, err)
+		}
+	}
+
+	actions, err := p.topologyActions(ctx, c, planned)
+	if err != nil || len(actions) == 0 {
+		return taskIds, err
+	}
+	if err = c.AddTempActions(ctx, actions); err != nil {
+		return taskIds, err
+	}
+	sdata, err := c.SaveTopology(ctx)
+	if err != nil {
+		return taskIds, err
+	}
+	return append(taskIds, sdata.Data.TaskIds...), nil
+}
+
+// stepKey identifies a plan step regardless of its detail
+type stepKey struct {
+	op   Op
+	kind Kind
+	name string
+}
+
+// createContainers creates containers whose parents already exist in a
+// single save
+func (p *Plan) createContainers(ctx context.Context, c *cvpgo.CvpClient, created []Container) error {
+	if len(created) == 0 {
+		return nil
+	}
+	containers, err := c.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]cvpgo.Container, len(containers))
+	for _, container := range containers {
+		byName[container.Name] = container
+	}
+	var actions []cvpgo.Action
+	for _, want := range created {
+		parent, ok := byName[parentName(want)]
+		if !ok {
+			return fmt.Errorf("No container named \"%s\" found", parentName(want))
+		}
+		actions = append(actions, cvpgo.NewContainerAction(want.Name, parent))
+	}
+	if err = c.AddTempActions(ctx, actions); err != nil {
+		return err
+	}
+	_, err = c.SaveTopology(ctx)
+	return err
+}
+
+// topologyActions returns the temp actions moving devices and assigning
+// configlets, resolved against CVP's state after the earlier changes
+func (p *Plan) topologyActions(ctx context.Context, c *cvpgo.CvpClient, planned map[stepKey]bool) ([]cvpgo.Action, error) {
+	current, err := fetchLive(ctx, c, p.state)
+	if err != nil {
+		return nil, err
+	}
+	containerByKey := make(map[string]cvpgo.Container, len(current.containers))
+	for _, container := range current.containers {
+		containerByKey[container.Key] = container
+	}
+	var actions []cvpgo.Action
+	for _, want := range p.state.Devices {
+		move := planned[stepKey{OpMove, KindDevice, want.Hostname}]
+		assign := planned[stepKey{OpAssign, KindDevice, want.Hostname}]
+		if !move && !assign {
+			continue
+		}
+		dev, ok := current.devices[want.Hostname]
+		if !ok {
+			return nil, fmt.Errorf("Device \"%s\" is not in the inventory", want.Hostname)
+		}
+		if move {
+			to := current.containers[want.Container]
+			actions = append(actions, cvpgo.MoveDeviceAction(dev, containerByKey[dev.ParentContainerKey], to))
+		}
+		if assign {
+			configlets, err := assigned(current.deviceConfiglets[want.Hostname], want.Configlets, current.configlets)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, cvpgo.DeviceConfigletsAction(dev, configlets))
+		}
+	}
+	for _, want := range p.state.Containers {
+		if !planned[stepKey{OpAssign, KindContainer, want.Name}] {
+			continue
+		}
+		configlets, err := assigned(current.containerConfiglets[want.Name], want.Configlets, current.configlets)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, cvpgo.ContainerConfigletsAction(current.containers[want.Name], configlets))
+	}
+	return actions, nil
+}
+
+// assigned returns the configlets to assign in place of current: the
+// configlets generated by builders are kept, the static ones are replaced
+// by the named configlets
+func assigned(current []cvpgo.Configlet, names []string, all map[string]cvpgo.Configlet) ([]cvpgo.Configlet, error) {
+	var configlets []cvpgo.Configlet
+	for _, cfglet := range current {
+		if !cfglet.IsStatic() {
+			configlets = append(configlets, cfglet)
+		}
+	}
+	for _, name := range names {
+		cfglet, ok := all[name]
+		if !ok {
+			return nil, fmt.Errorf("No configlet named \"%s\" found", name)
+		}
+		configlets = append(configlets, cfglet)
+	}
+	return configlets, nil
+}
@@ -0,0 +1,164 @@
+// Package desired computes and applies the changes needed to bring CVP in
+// line with a declared state of containers, devices and configlets, in the
+// manner of a plan/apply workflow:
+//
+//	state, err := desired.LoadFile("fabric.yaml")
+//	plan, err := desired.NewPlan(ctx, cvp, state)
+//	fmt.Print(plan)
+//	taskIds, err := plan.Apply(ctx, cvp)
+package desired
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RootContainer is the name of CVP's root container, containers without a
+// parent are created below it
+const RootContainer = "Tenant"
+
+// State is the declared state of CVP. Containers, devices and configlets
+// that are not declared are left untouched, the configlets assigned to a
+// declared container or device are exactly the ones listed for it.
+type State struct {
+	Containers []Container `yaml:"containers" json:"containers"`
+	Devices    []Device    `yaml:"devices" json:"devices"`
+	Configlets []Configlet `yaml:"configlets" json:"configlets"`
+}
+
+// Container is a container in the container tree
+type Container struct {
+	Name       string   `yaml:"name" json:"name"`
+	Parent     string   `yaml:"parent,omitempty" json:"parent,omitempty"`
+	Configlets []string `yaml:"configlets,omitempty" json:"configlets,omitempty"`
+}
+
+// Device is a device placed in a container. Devices are matched to the
+// inventory by hostname, a device missing from the inventory is added
+// using its IP address.
+type Device struct {
+	Hostname   string   `yaml:"hostname" json:"hostname"`
+	IPAddress  string   `yaml:"ipAddress,omitempty" json:"ipAddress,omitempty"`
+	Container  string   `yaml:"container" json:"container"`
+	Configlets []string `yaml:"configlets,omitempty" json:"configlets,omitempty"`
+}
+
+// Configlet is a static configlet
+type Configlet struct {
+	Name   string `yaml:"name" json:"name"`
+	Config string `yaml:"config" json:"config"`
+}
+
+// Load reads a state in YAML from r and validates it
+func Load(r io.Reader) (State, error) {
+	state := State{}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return state, err
+	}
+	if err = yaml.UnmarshalStrict(data, &state); err != nil {
+		return state, err
+	}
+	return state, state.Validate()
+}
+
+// LoadFile reads a state in YAML from the named file and validates it
+func LoadFile(name string) (State, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return State{}, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Validate checks that every name is unique and that every container,
+// device and configlet refers to containers and configlets that are either
+// declared or, for containers, the root container
+func (s State) Validate() error {
+	containers := map[string]bool{RootContainer: true}
+	for _, c := range s.Containers {
+		if c.Name == "" {
+			return fmt.Errorf("Container without a name")
+		}
+		if containers[c.Name] {
+			return fmt.Errorf("Container \"%s\" declared twice", c.Name)
+		}
+		containers[c.Name] = true
+	}
+	configlets := make(map[string]bool)
+	for _, c := range s.Configlets {
+		if c.Name == "" {
+			return fmt.Errorf("Configlet without a name")
+		}
+		if configlets[c.Name] {
+			return fmt.Errorf("Configlet \"%s\" declared twice", c.Name)
+		}
+		configlets[c.Name] = true
+	}
+	for _, c := range s.Containers {
+		if c.Parent != "" && !containers[c.Parent] {
+			return fmt.Errorf("Container \"%s\" has an undeclared parent \"%s\"", c.Name, c.Parent)
+		}
+		if err := checkConfiglets("Container", c.Name, c.Configlets, configlets); err != nil {
+			return err
+		}
+	}
+	if _, err := s.containerLevels(); err != nil {
+		return err
+	}
+	devices := make(map[string]bool)
+	for _, d := range s.Devices {
+		if d.Hostname == "" {
+			return fmt.Errorf("Device without a hostname")
+		}
+		if devices[d.Hostname] {
+			return fmt.Errorf("Device \"%s\" declared twice", d.Hostname)
+		}
+		devices[d.Hostname] = true
+		if !containers[d.Container] {
+			return fmt.Errorf("Device \"%s\" is in an undeclared container \"%s\"", d.Hostname, d.Container)
+		}
+		if err := checkConfiglets("Device", d.Hostname, d.Configlets, configlets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkConfiglets(kind, name string, assigned []string, declared map[string]bool) error {
+	for _, cfglet := range assigned {
+		if !declared[cfglet] {
+			return fmt.Errorf("%s \"%s\" is assigned an undeclared configlet \"%s\"", kind, name, cfglet)
+		}
+	}
+	return nil
+}
+
+// containerLevels groups the declared containers by their depth in the
+// tree, parents come before their children
+func (s State) containerLevels() ([][]Container, error) {
+	parents := make(map[string]string, len(s.Containers))
+	for _, c := range s.Containers {
+		parents[c.Name] = c.Parent
+	}
+	var levels [][]Container
+	for _, c := range s.Containers {
+		depth := 0
+		for p := c.Parent; p != "" && p != RootContainer; p = parents[p] {
+			depth++
+			if depth > len(s.Containers) {
+				return nil, fmt.Errorf("Container \"%s\" is its own ancestor", c.Name)
+			}
+		}
+		for len(levels) <= depth {
+			levels = append(levels, nil)
+		}
+		levels[depth] = append(levels[depth], c)
+	}
+	return levels, nil
+}
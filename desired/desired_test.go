@@ -0,0 +1,171 @@
+package desired
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cvpgo "github.com/fredhsu/cvpgo/client"
+)
+
+const testState = `
+configlets:
+  - name: base
+    config: |
+      username admin privilege 15 role network-admin secret admin
+  - name: leaf1-intf
+    config: |
+      interface Ethernet1
+         description uplink
+containers:
+  - name: DC1
+    configlets: [base]
+  - name: Leafs
+    parent: DC1
+devices:
+  - hostname: leaf1
+    ipAddress: 10.0.0.1
+    container: Leafs
+    configlets: [leaf1-intf]
+  - hostname: leaf2
+    ipAddress: 10.0.0.2
+    container: Leafs
+`
+
+func TestLoad(t *testing.T) {
+	state, err := Load(strings.NewReader(testState))
+	if err != nil {
+		t.Fatalf("Error loading state : %s", err)
+	}
+	if len(state.Containers) != 2 || len(state.Devices) != 2 || len(state.Configlets) != 2 {
+		t.Errorf("Unexpected state %+v", state)
+	}
+	levels, err := state.containerLevels()
+	if err != nil || len(levels) != 2 || levels[1][0].Name != "Leafs" {
+		t.Errorf("Unexpected container levels %+v (%v)", levels, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		state State
+		err   string
+	}{
+		{State{Containers: []Container{{Name: "A"}, {Name: "A"}}}, "declared twice"},
+		{State{Containers: []Container{{Name: "A", Parent: "B"}}}, "undeclared parent"},
+		{State{Containers: []Container{{Name: "A", Parent: "B"}, {Name: "B", Parent: "A"}}}, "its own ancestor"},
+		{State{Devices: []Device{{Hostname: "leaf1", Container: "A"}}}, "undeclared container"},
+		{State{Devices: []Device{{Hostname: "leaf1", Container: RootContainer, Configlets: []string{"x"}}}}, "undeclared configlet"},
+	}
+	for _, test := range tests {
+		err := test.state.Validate()
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Validate(%+v) = %v, want error containing %q", test.state, err, test.err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	state, err := Load(strings.NewReader(testState))
+	if err != nil {
+		t.Fatalf("Error loading state : %s", err)
+	}
+	current := &live{
+		containers: map[string]cvpgo.Container{
+			RootContainer: {Name: RootContainer, Key: "root"},
+			"DC1":         {Name: "DC1", Key: "container_1"},
+		},
+		devices: map[string]cvpgo.NetElement{
+			"leaf2": {Fqdn: "leaf2.example.com", SystemMacAddress: "00:1c:73:00:00:02", ParentContainerKey: "container_1"},
+		},
+		configlets: map[string]cvpgo.Configlet{
+			"base": {Name: "base", Config: "username admin privilege 15 role network-admin secret admin"},
+		},
+		containerConfiglets: map[string][]cvpgo.Configlet{
+			// assigned configlets report "Static", not ConfigletTypeStatic
+			"DC1": {{Name: "base", Type: "Static"}, {Name: "gen", Type: cvpgo.ConfigletTypeBuilder}},
+		},
+		deviceConfiglets: map[string][]cvpgo.Configlet{},
+	}
+	steps, err := diff(state, current)
+	if err != nil {
+		t.Fatalf("Error computing plan : %s", err)
+	}
+	want := []string{
+		"+ create configlet leaf1-intf",
+		"+ create container Leafs (in DC1)",
+		"+ create device leaf1 (10.0.0.1 in Leafs)",
+		"> move device leaf2 (DC1 -> Leafs)",
+		"~ assign device leaf1 ([] -> [leaf1-intf])",
+	}
+	var got []string
+	for _, s := range steps {
+		got = append(got, s.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected plan\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	plan := &Plan{Steps: steps}
+	if !strings.Contains(plan.String(), "Plan: 3 to create, 0 to update, 1 to move, 1 to assign") {
+		t.Errorf("Unexpected plan summary\n%s", plan)
+	}
+}
+
+func TestNewPlanInheritedConfiglets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/inventory/add/searchContainers.do":
+			fmt.Fprint(w, `{"total":2,"data":[{"name":"Tenant","key":"root"},{"name":"DC1","key":"c1","parentName":"Tenant"}]}`)
+		case "/configlet/getConfiglets.do":
+			fmt.Fprint(w, `{"total":2,"data":[{"name":"base","key":"k1","config":"hostname base\n"},{"name":"leaf1-intf","key":"k2","config":"interface Ethernet1\r\n"}]}`)
+		case "/inventory/getInventory.do":
+			fmt.Fprint(w, `{"total":1,"netElementList":[{"fqdn":"leaf1.example.com","systemMacAddress":"00:1c:73:00:00:01","parentContainerKey":"c1"}]}`)
+		case "/provisioning/getConfigletsByContainerId.do":
+			if r.URL.Query().Get("containerId") == "c1" {
+				fmt.Fprint(w, `{"configletList":[{"name":"base","key":"k1","type":"Static"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"configletList":[]}`)
+		case "/provisioning/getConfigletsByNetElementId.do":
+			// CVP also returns the configlet inherited from DC1
+			fmt.Fprint(w, `{"configletList":[{"name":"base","key":"k1","type":"Static"},{"name":"leaf1-intf","key":"k2","type":"Static"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := &cvpgo.CvpClient{BaseURL: srv.URL, Client: srv.Client()}
+	state := State{
+		Configlets: []Configlet{{Name: "base", Config: "hostname base"}, {Name: "leaf1-intf", Config: "interface Ethernet1\n"}},
+		Containers: []Container{{Name: "DC1", Configlets: []string{"base"}}},
+		Devices:    []Device{{Hostname: "leaf1", Container: "DC1", Configlets: []string{"leaf1-intf"}}},
+	}
+	plan, err := NewPlan(context.Background(), c, state)
+	if err != nil {
+		t.Fatalf("Error computing plan : %s", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("Expected no changes, got\n%s", plan)
+	}
+}
+
+func TestAssigned(t *testing.T) {
+	current := []cvpgo.Configlet{
+		{Name: "old", Key: "c1", Type: "Static"},
+		{Name: "gen", Key: "c2", Type: cvpgo.ConfigletTypeGenerated},
+	}
+	all := map[string]cvpgo.Configlet{"new": {Name: "new", Key: "c3"}}
+	configlets, err := assigned(current, []string{"new"}, all)
+	if err != nil {
+		t.Fatalf("Error resolving configlets : %s", err)
+	}
+	if len(configlets) != 2 || configlets[0].Name != "gen" || configlets[1].Name != "new" {
+		t.Errorf("Unexpected configlets %+v", configlets)
+	}
+	if _, err = assigned(current, []string{"missing"}, all); err == nil {
+		t.Errorf("Expected an error for a missing configlet")
+	}
+}
@@ -0,0 +1,268 @@
+package desired
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	cvpgo "github.com/fredhsu/cvpgo/client"
+)
+
+// Op is the operation of a plan step
+type Op string
+
+// Operations of a plan step
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpMove   Op = "move"
+	OpAssign Op = "assign"
+)
+
+// Kind is the kind of object a plan step changes
+type Kind string
+
+// Kinds of objects changed by a plan
+const (
+	KindContainer Kind = "container"
+	KindDevice    Kind = "device"
+	KindConfiglet Kind = "configlet"
+)
+
+// Step is one change of a plan
+type Step struct {
+	Op     Op
+	Kind   Kind
+	Name   string
+	Detail string
+}
+
+var opSymbols = map[Op]string{OpCreate: "+", OpUpdate: "~", OpMove: ">", OpAssign: "~"}
+
+func (s Step) String() string {
+	line := fmt.Sprintf("%s %s %s %s", opSymbols[s.Op], s.Op, s.Kind, s.Name)
+	if s.Detail != "" {
+		line += " (" + s.Detail + ")"
+	}
+	return line
+}
+
+// Plan is the list of changes that bring CVP to a declared state, in the
+// order Apply makes them
+type Plan struct {
+	Steps []Step
+	state State
+}
+
+// Empty reports whether CVP is already in the declared state
+func (p *Plan) Empty() bool {
+	return len(p.Steps) == 0
+}
+
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "No changes, CVP matches the declared state\n"
+	}
+	var b bytes.Buffer
+	for _, s := range p.Steps {
+		fmt.Fprintln(&b, s)
+	}
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to move, %d to assign\n",
+		p.count(OpCreate), p.count(OpUpdate), p.count(OpMove), p.count(OpAssign))
+	return b.String()
+}
+
+func (p *Plan) count(op Op) int {
+	n := 0
+	for _, s := range p.Steps {
+		if s.Op == op {
+			n++
+		}
+	}
+	return n
+}
+
+// live is the part of CVP's current state that a plan compares against
+type live struct {
+	containers map[string]cvpgo.Container
+	devices    map[string]cvpgo.NetElement
+	configlets map[string]cvpgo.Configlet
+	// configlets assigned to the declared containers and devices, by name
+	containerConfiglets map[string][]cvpgo.Configlet
+	deviceConfiglets    map[string][]cvpgo.Configlet
+}
+
+// NewPlan compares the declared state with CVP and returns the changes
+// needed to reach it
+func NewPlan(ctx context.Context, c *cvpgo.CvpClient, state State) (*Plan, error) {
+	if err := state.Validate(); err != nil {
+		return nil, err
+	}
+	current, err := fetchLive(ctx, c, state)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := diff(state, current)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{Steps: steps, state: state}, nil
+}
+
+func fetchLive(ctx context.Context, c *cvpgo.CvpClient, state State) (*live, error) {
+	current := &live{
+		containers:          make(map[string]cvpgo.Container),
+		devices:             make(map[string]cvpgo.NetElement),
+		configlets:          make(map[string]cvpgo.Configlet),
+		containerConfiglets: make(map[string][]cvpgo.Configlet),
+		deviceConfiglets:    make(map[string][]cvpgo.Configlet),
+	}
+	containers, err := c.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, container := range containers {
+		current.containers[container.Name] = container
+	}
+	configlets, _, err := c.ListConfiglets(ctx, cvpgo.ConfigletFilter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, cfglet := range configlets {
+		current.configlets[cfglet.Name] = cfglet
+	}
+	devices, err := c.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, dev := range devices {
+		current.devices[hostname(dev)] = dev
+	}
+	for _, want := range state.Containers {
+		container, ok := current.containers[want.Name]
+		if !ok {
+			continue
+		}
+		if current.containerConfiglets[want.Name], err = c.GetConfigletByContainerID(ctx, container.Key); err != nil {
+			return nil, err
+		}
+	}
+	// CVP's device lookup also returns the configlets a device inherits,
+	// only those assigned to the device itself are declared with it
+	inherited := make(map[string][]cvpgo.Configlet)
+	for _, want := range state.Devices {
+		dev, ok := current.devices[want.Hostname]
+		if !ok {
+			continue
+		}
+		if _, ok = inherited[dev.ParentContainerKey]; !ok {
+			if inherited[dev.ParentContainerKey], err = c.InheritedConfiglets(ctx, dev.ParentContainerKey); err != nil {
+				return nil, err
+			}
+		}
+		configlets, err := c.GetConfigletByDeviceIDWithContext(ctx, dev.SystemMacAddress)
+		if err != nil {
+			return nil, err
+		}
+		current.deviceConfiglets[want.Hostname] = cvpgo.WithoutConfiglets(configlets, inherited[dev.ParentContainerKey])
+	}
+	return current, nil
+}
+
+// hostname returns the short hostname of a device
+func hostname(dev cvpgo.NetElement) string {
+	return strings.SplitN(dev.Fqdn, ".", 2)[0]
+}
+
+// diff returns the steps turning current into the declared state
+func diff(state State, current *live) ([]Step, error) {
+	var steps []Step
+	for _, want := range state.Configlets {
+		cfglet, ok := current.configlets[want.Name]
+		if !ok {
+			steps = append(steps, Step{Op: OpCreate, Kind: KindConfiglet, Name: want.Name})
+		} else if !cvpgo.SameConfig(cfglet.Config, want.Config) {
+			steps = append(steps, Step{Op: OpUpdate, Kind: KindConfiglet, Name: want.Name, Detail: "config changed"})
+		}
+	}
+	levels, err := state.containerLevels()
+	if err != nil {
+		return nil, err
+	}
+	for _, level := range levels {
+		for _, want := range level {
+			if _, ok := current.containers[want.Name]; !ok {
+				steps = append(steps, Step{Op: OpCreate, Kind: KindContainer, Name: want.Name, Detail: "in " + parentName(want)})
+			}
+		}
+	}
+	for _, want := range state.Devices {
+		if _, ok := current.devices[want.Hostname]; ok {
+			continue
+		}
+		if want.IPAddress == "" {
+			return nil, fmt.Errorf("Device \"%s\" is not in the inventory and has no IP address", want.Hostname)
+		}
+		steps = append(steps, Step{Op: OpCreate, Kind: KindDevice, Name: want.Hostname, Detail: want.IPAddress + " in " + want.Container})
+	}
+	containerNames := make(map[string]string, len(current.containers))
+	for _, container := range current.containers {
+		containerNames[container.Key] = container.Name
+	}
+	for _, want := range state.Devices {
+		dev, ok := current.devices[want.Hostname]
+		if !ok {
+			continue
+		}
+		if from := containerNames[dev.ParentContainerKey]; from != want.Container {
+			steps = append(steps, Step{Op: OpMove, Kind: KindDevice, Name: want.Hostname, Detail: from + " -> " + want.Container})
+		}
+	}
+	for _, want := range state.Containers {
+		if have := staticNames(current.containerConfiglets[want.Name]); !equal(have, want.Configlets) {
+			steps = append(steps, Step{Op: OpAssign, Kind: KindContainer, Name: want.Name, Detail: assignDetail(have, want.Configlets)})
+		}
+	}
+	for _, want := range state.Devices {
+		if have := staticNames(current.deviceConfiglets[want.Hostname]); !equal(have, want.Configlets) {
+			steps = append(steps, Step{Op: OpAssign, Kind: KindDevice, Name: want.Hostname, Detail: assignDetail(have, want.Configlets)})
+		}
+	}
+	return steps, nil
+}
+
+func parentName(c Container) string {
+	if c.Parent == "" {
+		return RootContainer
+	}
+	return c.Parent
+}
+
+// staticNames returns the names of the static configlets, the only ones a
+// state declares
+func staticNames(configlets []cvpgo.Configlet) []string {
+	var names []string
+	for _, cfglet := range configlets {
+		if cfglet.IsStatic() {
+			names = append(names, cfglet.Name)
+		}
+	}
+	return names
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func assignDetail(have, want []string) string {
+	return fmt.Sprintf("[%s] -> [%s]", strings.Join(have, " "), strings.Join(want, " "))
+}
@@ -0,0 +1,157 @@
+// Package eosconfig parses EOS configs into a tree of stanzas, so that
+// configs can be looked up, merged and compared by structure rather than
+// as text.
+package eosconfig
+
+import (
+	"strings"
+)
+
+// Indent is the indentation EOS uses for each level of a config stanza
+const Indent = "   "
+
+// Node is a config line together with the lines nested below it. A node
+// with children is a stanza such as "interface Ethernet1".
+type Node struct {
+	Line     string
+	Children []*Node
+	// Source is the name of the config the line comes from. In a merged
+	// config it is the last config that set the line.
+	Source string
+	// Sources lists, in merge order, every config that set the line
+	Sources []string
+	// Overridden holds the lines this line replaced in a merge, such as
+	// "description a" replaced by "description b", see Key
+	Overridden []*Node
+}
+
+// IsSection reports whether the node has nested lines
+func (n *Node) IsSection() bool {
+	return len(n.Children) > 0
+}
+
+// Child returns the child with exactly the given line, or nil
+func (n *Node) Child(line string) *Node {
+	for _, child := range n.Children {
+		if child.Line == line {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *Node) clone() *Node {
+	c := &Node{Line: n.Line, Source: n.Source, Sources: append([]string(nil), n.Sources...)}
+	c.Overridden = append(c.Overridden, n.Overridden...)
+	for _, child := range n.Children {
+		c.Children = append(c.Children, child.clone())
+	}
+	return c
+}
+
+// Config is a parsed EOS config, the children of Root are the top level
+// lines and stanzas
+type Config struct {
+	Name string
+	Root *Node
+}
+
+// Normalise strips comments, blank lines and trailing whitespace from an
+// EOS config and re-indents it with the standard EOS indentation
+func Normalise(text string) []string {
+	var result []string
+	// indentation widths of the stanzas enclosing the current line
+	var open []int
+	for _, line := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		line = strings.TrimRight(line, " \t")
+		content := strings.TrimLeft(line, " \t")
+		if content == "" || strings.HasPrefix(content, "!") || content == "end" {
+			continue
+		}
+		indent := len(line) - len(content)
+		for len(open) > 0 && open[len(open)-1] >= indent {
+			open = open[:len(open)-1]
+		}
+		result = append(result, strings.Repeat(Indent, len(open))+content)
+		open = append(open, indent)
+	}
+	return result
+}
+
+// Parse parses an EOS config, name is recorded as the source of every line
+func Parse(name, text string) *Config {
+	root := &Node{}
+	stack := []*Node{root}
+	for _, line := range Normalise(text) {
+		content := strings.TrimLeft(line, " ")
+		depth := (len(line) - len(content)) / len(Indent)
+		if depth > len(stack)-1 {
+			depth = len(stack) - 1
+		}
+		stack = stack[:depth+1]
+		node := &Node{Line: content, Source: name}
+		if name != "" {
+			node.Sources = []string{name}
+		}
+		parent := stack[len(stack)-1]
+		parent.Children = append(parent.Children, node)
+		stack = append(stack, node)
+	}
+	return &Config{Name: name, Root: root}
+}
+
+// Lines returns the config as normalised lines
+func (c *Config) Lines() []string {
+	var lines []string
+	c.Walk(func(path []string, n *Node) {
+		lines = append(lines, strings.Repeat(Indent, len(path)-1)+n.Line)
+	})
+	return lines
+}
+
+// String serialises the config in normalised form
+func (c *Config) String() string {
+	lines := c.Lines()
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Walk calls fn for every line of the config in order, with the path of
+// lines leading to it from the top level, the line itself included
+func (c *Config) Walk(fn func(path []string, n *Node)) {
+	walk(c.Root.Children, nil, fn)
+}
+
+func walk(nodes []*Node, path []string, fn func([]string, *Node)) {
+	for _, n := range nodes {
+		p := append(path[:len(path):len(path)], n.Line)
+		fn(p, n)
+		walk(n.Children, p, fn)
+	}
+}
+
+// Lookup returns the line at the given path of exact lines, such as
+// Lookup("router bgp 65000", "address-family ipv4"), or nil
+func (c *Config) Lookup(path ...string) *Node {
+	n := c.Root
+	for _, line := range path {
+		if n = n.Child(line); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// Sections returns the top level lines starting with the given words, such
+// as Sections("interface") for every interface or Sections("router bgp")
+func (c *Config) Sections(prefix string) []*Node {
+	var result []*Node
+	for _, n := range c.Root.Children {
+		if n.Line == prefix || strings.HasPrefix(n.Line, prefix+" ") {
+			result = append(result, n)
+		}
+	}
+	return result
+}
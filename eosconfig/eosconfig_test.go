@@ -0,0 +1,143 @@
+package eosconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalise(t *testing.T) {
+	config := "! generated\r\nhostname A  \n\ninterface Ethernet1\n  description uplink\n  !\n  shutdown\nrouter bgp 65000\n    neighbor 1.1.1.1 remote-as 1\n    address-family ipv4\n       neighbor 1.1.1.1 activate\nend\n"
+	expected := []string{
+		"hostname A",
+		"interface Ethernet1",
+		"   description uplink",
+		"   shutdown",
+		"router bgp 65000",
+		"   neighbor 1.1.1.1 remote-as 1",
+		"   address-family ipv4",
+		"      neighbor 1.1.1.1 activate",
+	}
+	if got := Normalise(config); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected normalised config %q", got)
+	}
+	if got := Parse("", config).Lines(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Parsed config does not serialise back %q", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	config := Parse("base", "interface Ethernet1\n   shutdown\ninterface Ethernet2\nrouter bgp 65000\n   address-family ipv4\n      network 10.0.0.0/8\n")
+	if n := config.Lookup("router bgp 65000", "address-family ipv4", "network 10.0.0.0/8"); n == nil || n.Source != "base" {
+		t.Errorf("Unexpected lookup result %+v", n)
+	}
+	if n := config.Lookup("router bgp 65001"); n != nil {
+		t.Errorf("Unexpected lookup result %+v", n)
+	}
+	if n := config.Sections("interface"); len(n) != 2 || !n[0].IsSection() || n[1].IsSection() {
+		t.Errorf("Unexpected interfaces %+v", n)
+	}
+	if n := config.Sections("router bgp"); len(n) != 1 {
+		t.Errorf("Unexpected router bgp sections %+v", n)
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		stanza, line, want string
+	}{
+		{"interface Ethernet1", "description uplink", "description"},
+		{"interface Ethernet1", "no shutdown", "shutdown"},
+		{"interface Ethernet1", "ip address 10.0.0.1/24", "ip address"},
+		{"interface Vlan10", "ip address virtual 10.0.0.1/24", "ip address virtual"},
+		{"interface Vlan10", "ip address 10.0.1.1/24 secondary", "ip address 10.0.1.1/24 secondary"},
+		{"interface Ethernet1", "vrf PROD", "vrf"},
+		{"interface Ethernet1", "switchport trunk allowed vlan 10,20", "switchport trunk allowed vlan"},
+		{"interface Ethernet1", "switchport trunk allowed vlan add 30", "switchport trunk allowed vlan add 30"},
+		{"interface Ethernet1", "switchport trunk allowed vlan remove 20", "switchport trunk allowed vlan remove 20"},
+		{"vlan 10", "name users", "name"},
+		{"", "hostname leaf1", "hostname"},
+		{"", "ntp server 10.0.0.1", "ntp server 10.0.0.1"},
+		{"", "vrf instance PROD", "vrf instance PROD"},
+		{"", "description uplink", "description uplink"},
+		{"router bgp 65000", "vrf PROD", "vrf PROD"},
+	}
+	for _, test := range tests {
+		if got := Key(test.stanza, test.line); got != test.want {
+			t.Errorf("Key(%q, %q) = %q, want %q", test.stanza, test.line, got, test.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := Parse("base", "hostname leaf\nntp server 10.0.0.1\ninterface Ethernet1\n   description old\n   shutdown\n")
+	device := Parse("leaf1", "hostname leaf1\nntp server 10.0.0.2\ninterface Ethernet1\n   description uplink\n   no shutdown\ninterface Ethernet2\n   mtu 9214\n")
+	merged := Merge("leaf1-designed", base, device)
+	want := `hostname leaf1
+ntp server 10.0.0.1
+interface Ethernet1
+   description uplink
+   no shutdown
+ntp server 10.0.0.2
+interface Ethernet2
+   mtu 9214
+`
+	if got := merged.String(); got != want {
+		t.Errorf("Unexpected merged config\n%s\nwant\n%s", got, want)
+	}
+	intf := merged.Lookup("interface Ethernet1")
+	if intf.Source != "leaf1" || strings.Join(intf.Sources, ",") != "base,leaf1" {
+		t.Errorf("Unexpected stanza sources %s %v", intf.Source, intf.Sources)
+	}
+	desc := merged.Lookup("interface Ethernet1", "description uplink")
+	if desc.Source != "leaf1" || len(desc.Overridden) != 1 || desc.Overridden[0].Line != "description old" || desc.Overridden[0].Source != "base" {
+		t.Errorf("Unexpected override %+v", desc)
+	}
+	if base.Lookup("interface Ethernet1", "description old") == nil {
+		t.Errorf("Merge changed its input")
+	}
+}
+
+func TestMergeVrfs(t *testing.T) {
+	prod := Parse("prod", "vrf instance PROD\ninterface Ethernet1\n   vrf PROD\n")
+	dev := Parse("dev", "vrf instance DEV\ninterface Ethernet1\n   vrf DEV\n")
+	merged := Merge("merged", prod, dev)
+	want := `vrf instance PROD
+interface Ethernet1
+   vrf DEV
+vrf instance DEV
+`
+	if got := merged.String(); got != want {
+		t.Errorf("Unexpected merged config\n%s\nwant\n%s", got, want)
+	}
+	if n := merged.Lookup("vrf instance PROD"); n == nil || len(n.Overridden) != 0 || len(n.Sources) != 1 {
+		t.Errorf("Unexpected VRF %+v", n)
+	}
+	if n := merged.Lookup("interface Ethernet1", "vrf DEV"); n == nil || len(n.Overridden) != 1 || n.Overridden[0].Line != "vrf PROD" {
+		t.Errorf("Unexpected interface VRF %+v", n)
+	}
+}
+
+func TestMergeTrunkVlans(t *testing.T) {
+	base := Parse("base", "interface Ethernet1\n   switchport trunk allowed vlan 10,20\n")
+	add := Parse("add", "interface Ethernet1\n   switchport trunk allowed vlan add 30\n   switchport trunk allowed vlan remove 20\n")
+	merged := Merge("merged", base, add)
+	want := `interface Ethernet1
+   switchport trunk allowed vlan 10,20
+   switchport trunk allowed vlan add 30
+   switchport trunk allowed vlan remove 20
+`
+	if got := merged.String(); got != want {
+		t.Errorf("Unexpected merged config\n%s\nwant\n%s", got, want)
+	}
+	merged.Walk(func(path []string, n *Node) {
+		if len(n.Overridden) != 0 {
+			t.Errorf("Unexpected override of %v by %s", n.Overridden[0].Line, n.Line)
+		}
+	})
+	replace := Parse("replace", "interface Ethernet1\n   switchport trunk allowed vlan 40\n")
+	merged = Merge("merged", base, replace)
+	if n := merged.Lookup("interface Ethernet1", "switchport trunk allowed vlan 40"); n == nil || len(n.Overridden) != 1 {
+		t.Errorf("Expected the allowed VLANs to be replaced\n%s", merged)
+	}
+}
@@ -0,0 +1,125 @@
+package eosconfig
+
+import (
+	"strings"
+)
+
+// singleValued lists, by the first word of the stanza they are in, the
+// commands that take a single value there, setting one again replaces its
+// previous value. The top level has the empty stanza.
+var singleValued = map[string][]string{
+	"": {
+		"hostname",
+		"ip domain-name",
+		"dns domain",
+		"ip virtual-router mac-address",
+		"spanning-tree mode",
+	},
+	"interface": {
+		"description",
+		// before "ip address", which it starts with
+		"ip address virtual",
+		"ip address",
+		"mtu",
+		"speed",
+		"load-interval",
+		"channel-group",
+		"vrf forwarding",
+		"vrf",
+		"encapsulation dot1q vlan",
+		"switchport mode",
+		"switchport access vlan",
+		"switchport trunk native vlan",
+		"switchport trunk allowed vlan",
+	},
+	"vlan": {
+		"name",
+	},
+	"router": {
+		"router-id",
+		"maximum-paths",
+	},
+	"mlag": {
+		"domain-id",
+		"local-interface",
+		"peer-address",
+		"peer-link",
+	},
+}
+
+// addRemove lists the single valued commands that also take "add" and
+// "remove" forms, which change the value set before them rather than
+// replace it
+var addRemove = map[string]bool{
+	"switchport trunk allowed vlan": true,
+}
+
+// Key returns what a line under the given stanza sets, two lines with the
+// same key under the same stanza override each other. The key of a
+// negated line is the line it negates, so "no shutdown" overrides
+// "shutdown", and the key of a command that takes a single value in the
+// stanza, such as "description uplink" under an interface, is the command.
+// Other lines, including the add and remove forms of such commands, only
+// override identical lines. Top level lines have an empty
+// stanza.
+func Key(stanza, line string) string {
+	line = strings.TrimPrefix(line, "no ")
+	if strings.HasSuffix(line, " secondary") {
+		return line
+	}
+	kind := strings.SplitN(stanza, " ", 2)[0]
+	for _, cmd := range singleValued[kind] {
+		if line == cmd || strings.HasPrefix(line, cmd+" ") {
+			if addRemove[cmd] && (strings.HasPrefix(line, cmd+" add ") || strings.HasPrefix(line, cmd+" remove ")) {
+				return line
+			}
+			return cmd
+		}
+	}
+	return line
+}
+
+// Merge merges configs in order, the way EOS applies configlets in
+// assignment order. Stanzas with the same line are merged, a line replaces
+// an earlier line with the same Key under the same stanza and new lines
+// are appended. The merged lines record which configs set them.
+func Merge(name string, configs ...*Config) *Config {
+	merged := &Config{Name: name, Root: &Node{}}
+	for _, config := range configs {
+		mergeInto(merged.Root, config.Root)
+	}
+	return merged
+}
+
+func mergeInto(dst, src *Node) {
+	for _, s := range src.Children {
+		if existing := dst.Child(s.Line); existing != nil {
+			existing.Source = s.Source
+			existing.Sources = append(existing.Sources, s.Sources...)
+			mergeInto(existing, s)
+			continue
+		}
+		if !s.IsSection() {
+			if i := overriddenBy(dst, s); i >= 0 {
+				previous := dst.Children[i]
+				replacement := s.clone()
+				replacement.Overridden = append(previous.Overridden, &Node{Line: previous.Line, Source: previous.Source, Sources: previous.Sources})
+				dst.Children[i] = replacement
+				continue
+			}
+		}
+		dst.Children = append(dst.Children, s.clone())
+	}
+}
+
+// overriddenBy returns the index of the line under dst that s overrides,
+// or -1
+func overriddenBy(dst, s *Node) int {
+	key := Key(dst.Line, s.Line)
+	for i, child := range dst.Children {
+		if !child.IsSection() && Key(dst.Line, child.Line) == key {
+			return i
+		}
+	}
+	return -1
+}